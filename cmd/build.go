@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/internal/db"
+)
+
+// buildCmd represents the db build command
+var buildCmd = &cobra.Command{
+	Use:   "build <output.nbdb> --db dbs",
+	Short: "build a nbdb database merged from several databases",
+	Long: `build a nbdb database merged from several databases.
+
+The ranges of every database given by --db are merged into one sorted range file.
+Each field (country, country_code, region, city, isp) is taken from the first database
+in --db order that has a value, use --prefer to choose other databases for a field.
+Supported databases: qqwry, zxipv6wry, mmdb, ip2region and nbdb.
+
+Use the result with: nabili db import <output.nbdb> --as <name> --register`,
+	Example: "nabili db build merged.nbdb --db qqwry,zxipv6wry\nnabili db build merged.nbdb --db qqwry,zxipv6wry,geoip --prefer country_code=geoip --prefer city=qqwry,geoip",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dbs, _ := cmd.Flags().GetStringSlice("db")
+		prefers, _ := cmd.Flags().GetStringArray("prefer")
+
+		precedence := make(map[string][]string)
+		for _, prefer := range prefers {
+			field, sources, found := strings.Cut(prefer, "=")
+			if !found || sources == "" {
+				log.Fatalf("--prefer 格式应为 field=db1,db2: %s\n", prefer)
+			}
+			precedence[field] = strings.Split(sources, ",")
+		}
+
+		if err := db.Build(constant.WorkPath(args[0]), db.BuildOptions{Sources: dbs, Precedence: precedence}); err != nil {
+			log.Fatalln("生成失败:", err)
+		}
+	},
+}
+
+func init() {
+	buildCmd.Flags().StringSlice("db", []string{"qqwry", "zxipv6wry"}, "databases to merge, earlier ones take precedence")
+	buildCmd.Flags().StringArray("prefer", nil, "field precedence like city=geoip,qqwry, can be repeated")
+	dbCmd.AddCommand(buildCmd)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/internal/db"
+)
+
+// exportCmd represents the db export command
+var exportCmd = &cobra.Command{
+	Use:   "export <bundle.tar.gz> [--db dbs]",
+	Short: "pack local database files with a manifest for offline hosts",
+	Long: `pack local database files with a manifest for offline hosts.
+
+The manifest records name, format, SHA-256, source url and fetch time of every database.
+Install the bundle with "nabili db import-bundle", or extract it to a dir or web server
+and use it with "nabili update --mirror".`,
+	Example: "nabili db export bundle.tar.gz\nnabili db export bundle.tar.gz --db qqwry,zxipv6wry",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		DBs, _ := cmd.Flags().GetString("db")
+
+		var DBNameArray []string
+		if DBs != "" {
+			DBNameArray = strings.Split(DBs, ",")
+		}
+		if err := db.ExportBundle(constant.WorkPath(args[0]), DBNameArray...); err != nil {
+			log.Fatalln("导出失败:", err)
+		}
+	},
+}
+
+// importBundleCmd represents the db import-bundle command
+var importBundleCmd = &cobra.Command{
+	Use:     "import-bundle <bundle.tar.gz>",
+	Short:   "verify and install a bundle made by nabili db export",
+	Long:    `verify and install a bundle made by nabili db export. Nothing is installed if any file fails verification.`,
+	Example: "nabili db import-bundle bundle.tar.gz",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := db.ImportBundle(constant.WorkPath(args[0])); err != nil {
+			log.Fatalln("导入失败:", err)
+		}
+	},
+}
+
+func init() {
+	exportCmd.Flags().String("db", "", "choose dbs you want to export, all configured dbs by default")
+	dbCmd.AddCommand(exportCmd)
+	dbCmd.AddCommand(importBundleCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/internal/db"
+)
+
+// convertCmd represents the db convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert --from db --to format <output>",
+	Short: "convert a database to mmdb, nbdb, csv, jsonl, qqwry or zxipv6wry",
+	Long: `convert a database to mmdb, nbdb, csv, jsonl, qqwry or zxipv6wry.
+
+Every range of the database is written with the normalized fields
+(country, country_code, region, city, isp). The mmdb output uses the GeoIP2 City layout
+and can be read by nginx geoip2, Logstash and other MaxMind compatible tools.
+The qqwry output holds the IPv4 ranges with GBK strings, the zxipv6wry output holds
+the IPv6 ranges, which must be aligned to /64.
+--from is a configured database name or a database file,
+--to defaults to the output file extension, use - as output to write csv or jsonl to stdout.`,
+	Example: "nabili db convert --from qqwry --to mmdb qqwry.mmdb\nnabili db convert --from zxipv6wry zxipv6wry.csv\nnabili db convert --from ./qqwry.dat --to jsonl -\nnabili db convert --from ip2region --to qqwry ip2region.dat",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		lang, _ := cmd.Flags().GetString("lang")
+
+		output := args[0]
+		if output != "-" {
+			output = constant.WorkPath(output)
+		}
+		if err := db.Convert(output, db.ConvertOptions{From: from, To: to, Lang: lang}); err != nil {
+			log.Fatalln("转换失败:", err)
+		}
+	},
+}
+
+func init() {
+	convertCmd.Flags().String("from", "", "database name or file to convert")
+	convertCmd.Flags().String("to", "", "target format: mmdb, nbdb, csv, jsonl, qqwry or zxipv6wry")
+	convertCmd.Flags().String("lang", "", "language of names in mmdb output, defaults to the language of the database")
+	_ = convertCmd.MarkFlagRequired("from")
+	dbCmd.AddCommand(convertCmd)
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "manage local ip databases",
+	Long:  `manage local ip databases`,
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+}
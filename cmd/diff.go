@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/internal/db"
+)
+
+// diffCmd represents the db diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "show the ranges added, removed or changed between two databases",
+	Long: `show the ranges added, removed or changed between two databases.
+
+Both arguments are database names or files of any format nabili can iterate
+(qqwry, zxipv6wry, mmdb, ip2region, nbdb), usually two versions of the same source.
+A summary by country and province is printed after the ranges.`,
+	Example: "nabili db diff qqwry-old.dat qqwry.dat\nnabili db diff qqwry-old.dat qqwry --summary\nnabili db diff old.mmdb new.mmdb --json",
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		isJson, _ := cmd.Flags().GetBool("json")
+		summary, _ := cmd.Flags().GetBool("summary")
+
+		if err := db.Diff(os.Stdout, args[0], args[1], db.DiffOptions{JSON: isJson, SummaryOnly: summary}); err != nil {
+			log.Fatalln("比较失败:", err)
+		}
+	},
+}
+
+func init() {
+	diffCmd.Flags().BoolP("json", "j", false, "output in JSON lines")
+	diffCmd.Flags().Bool("summary", false, "only print the summary by location")
+	dbCmd.AddCommand(diffCmd)
+}
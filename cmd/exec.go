@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/pkg/entity"
+	"github.com/abc1763613206/nabili/pkg/pty"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- command [args...]",
+	Short: "run a command and annotate its output as it streams",
+	Long: `run a command and annotate its output as it streams.
+
+The command runs under a pseudo terminal, so it keeps its line buffering and colors,
+stdout and stderr are annotated separately. Signals like Ctrl-C are passed to the
+command and nabili exits with its exit code. Unfinished lines, like a traceroute hop
+waiting for its probes, are shown right away and annotated once they are finished.
+Pipes are used on platforms without pseudo terminals, or with --no-pty.`,
+	Example: "nabili exec -- traceroute 1.1.1.1\nnabili exec -- ping -c 4 dns.google\nnabili exec --annotate eol -- mtr --report 8.8.8.8",
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		annotate, _ := cmd.Flags().GetString("annotate")
+		noPTY, _ := cmd.Flags().GetBool("no-pty")
+		mode, err := entity.ParseAnnotateMode(annotate)
+		if err != nil {
+			log.Fatalln("--annotate:", err)
+		}
+		os.Exit(runAnnotated(args, mode, !noPTY))
+	},
+}
+
+// runAnnotated runs the command with its output annotated and returns its exit code
+func runAnnotated(args []string, mode entity.AnnotateMode, usePTY bool) int {
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdin = os.Stdin
+
+	var stdout, stderr io.ReadCloser
+	var err error
+	if usePTY {
+		stdout, stderr, err = pty.Start(c)
+		if errors.Is(err, pty.ErrUnsupported) {
+			usePTY = false
+		}
+	}
+	if !usePTY {
+		stdout, stderr, err = startPiped(c)
+	}
+	if err != nil {
+		log.Printf("%s 启动失败: %v\n", args[0], err)
+		return 127
+	}
+
+	signals := make(chan os.Signal, 1)
+	forward := pty.Signals
+	if pty.ResizeSignal != nil {
+		forward = append(forward[:len(forward):len(forward)], pty.ResizeSignal)
+	}
+	signal.Notify(signals, forward...)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			if sig == pty.ResizeSignal {
+				if f, ok := stdout.(*os.File); ok {
+					pty.InheritSize(os.Stdout, f)
+				}
+				if f, ok := stderr.(*os.File); ok {
+					pty.InheritSize(os.Stderr, f)
+				}
+				continue
+			}
+			_ = c.Process.Signal(sig)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, s := range []struct {
+		src io.ReadCloser
+		dst *os.File
+		out io.Writer
+	}{{stdout, os.Stdout, color.Output}, {stderr, os.Stderr, color.Error}} {
+		wg.Add(1)
+		go func(src io.ReadCloser, dst *os.File, out io.Writer) {
+			defer wg.Done()
+			defer src.Close()
+			live := isatty.IsTerminal(dst.Fd()) || isatty.IsCygwinTerminal(dst.Fd())
+			r := &entity.Renderer{Mode: mode}
+			if err := r.Stream(out, src, live, pty.IsEOF); err != nil {
+				log.Println(err)
+			}
+		}(s.src, s.dst, s.out)
+	}
+
+	// pipes must be drained before Wait closes them
+	wg.Wait()
+	return exitCode(c.Wait())
+}
+
+func startPiped(c *exec.Cmd) (stdout, stderr io.ReadCloser, err error) {
+	if stdout, err = c.StdoutPipe(); err != nil {
+		return nil, nil, err
+	}
+	if stderr, err = c.StderrPipe(); err != nil {
+		return nil, nil, err
+	}
+	return stdout, stderr, c.Start()
+}
+
+// exitCode returns the exit code of a finished command, 128+n if it was killed by signal n
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		return 0
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return exitErr.ExitCode()
+}
+
+func init() {
+	execCmd.Flags().String("annotate", string(entity.AnnotateInline), "Where to put the annotations: inline, replace, eol or column")
+	execCmd.Flags().Bool("no-pty", false, "Run the command with pipes instead of a pseudo terminal")
+	rootCmd.AddCommand(execCmd)
+}
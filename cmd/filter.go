@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/pkg/charset"
+	"github.com/abc1763613206/nabili/pkg/entity"
+)
+
+// filterCmd represents the filter command
+var filterCmd = &cobra.Command{
+	Use:   "filter [expression]",
+	Short: "output the input lines with an entity matching the expression, unmodified",
+	Long: `output the input lines with an entity matching the expression, unmodified.
+
+The expression compares the fields of each IP or domain found in a line:
+type, ip, source, class, domain, text and the normalized location fields
+country, country_code, region, city and isp.
+
+	= == !=   equal, not equal, ignoring case
+	~ !~      contains, does not contain, ignoring case
+	=~        matches the regexp
+
+Comparisons are combined with and, or, not and parentheses (&& || ! also work),
+quote values with spaces or operators. --country matches the country name or code,
+--isp matches a part of the ISP, they are combined with the expression by and.`,
+	Example: `cat access.log | nabili filter --country US --isp Amazon
+cat access.log | nabili filter 'country = 中国 and not (isp ~ 电信 or isp ~ 联通)'
+cat access.log | nabili filter -v 'class = private'
+tail -f nginx.log | nabili filter "ip =~ '^10\.' or city = 深圳"`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		invert, _ := cmd.Flags().GetBool("invert")
+		countries, _ := cmd.Flags().GetStringSlice("country")
+		isps, _ := cmd.Flags().GetStringSlice("isp")
+
+		var parts []string
+		if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
+			parts = append(parts, args[0])
+		}
+		if len(countries) > 0 {
+			var or []string
+			for _, c := range countries {
+				q := strconv.Quote(c)
+				or = append(or, fmt.Sprintf("country = %s or country_code = %s", q, q))
+			}
+			parts = append(parts, "("+strings.Join(or, " or ")+")")
+		}
+		if len(isps) > 0 {
+			var or []string
+			for _, isp := range isps {
+				or = append(or, "isp ~ "+strconv.Quote(isp))
+			}
+			parts = append(parts, "("+strings.Join(or, " or ")+")")
+		}
+		if len(parts) == 0 {
+			log.Fatalln("需要过滤表达式或 --country、--isp 条件")
+		}
+		if len(parts) > 1 {
+			parts[0] = "(" + parts[0] + ")"
+		}
+		expr, err := entity.ParseExpr(strings.Join(parts, " and "))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		enc, err := inputEncoding(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		stdin := charset.NewScanner(os.Stdin, enc)
+		stdin.Buffer(nil, 1024*1024)
+		for stdin.Scan() {
+			if entity.MatchLine(expr, entity.ParseLine(stdin.Text())) != invert {
+				_, _ = os.Stdout.Write(stdin.Bytes())
+			}
+		}
+		if err := stdin.Err(); err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+func init() {
+	addEncodingFlags(filterCmd, false)
+	filterCmd.Flags().BoolP("invert", "v", false, "Output the lines that do not match")
+	filterCmd.Flags().StringSlice("country", nil, "Match the country name or code, may be repeated")
+	filterCmd.Flags().StringSlice("isp", nil, "Match a part of the ISP, may be repeated")
+	rootCmd.AddCommand(filterCmd)
+}
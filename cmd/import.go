@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/internal/db"
+)
+
+// importCmd represents the db import command
+var importCmd = &cobra.Command{
+	Use:   "import <path> [--as name]",
+	Short: "import local database files for offline hosts",
+	Long: `import local database files for offline hosts.
+
+The format is detected from the file content (qqwry, zxipv6wry, mmdb, ip2region xdb, ipip ipdb, ip2location BIN),
+the file is validated and copied into the data dir under the configured file name.
+Without --as the database with the same file name is used, or else the only one of the detected format.
+If path is a directory, every database file in it is imported.`,
+	Example: "nabili db import ./qqwry.dat\nnabili db import ./GeoLite2-City.mmdb --as geoip\nnabili db import ./GeoLite2-ASN.mmdb --as asn --register\nnabili db import ./databases/",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		as, _ := cmd.Flags().GetString("as")
+		register, _ := cmd.Flags().GetBool("register")
+
+		if err := db.Import(constant.WorkPath(args[0]), db.ImportOptions{As: as, Register: register}); err != nil {
+			log.Fatalln("导入失败:", err)
+		}
+	},
+}
+
+func init() {
+	importCmd.Flags().String("as", "", "name of the database to import into")
+	importCmd.Flags().Bool("register", false, "add a new database entry to the config if --as does not exist")
+	dbCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/internal/db"
+	"github.com/abc1763613206/nabili/pkg/common"
+	"github.com/abc1763613206/nabili/pkg/dbif"
+	"github.com/abc1763613206/nabili/pkg/entity"
+	"github.com/abc1763613206/nabili/pkg/lineedit"
+)
+
+const replHelp = `输入 IP、域名或任意文本查询，quit 或 Ctrl-D 退出
+
+  :db4 [name]            查看或切换 IPv4 数据库，如 :db4 ip2region
+  :db6 [name]            查看或切换 IPv6 数据库
+  :compare [name,...]    同时列出各数据库的查询结果，如 :compare qqwry,geoip，:compare off 关闭
+  :json [on|off]         切换 JSON 输出
+  :info                  查看当前设置
+  :help                  显示本帮助
+  :quit                  退出
+
+Tab 补全命令和数据库名，上下方向键浏览历史记录`
+
+var replCommands = []string{":compare", ":db4", ":db6", ":help", ":info", ":json", ":quit"}
+
+// repl is the interactive mode, used when nabili is run without arguments on a terminal
+type repl struct {
+	editor   *lineedit.Editor
+	renderer *entity.Renderer
+	where    entity.Where
+	json     bool
+	compare  []string
+}
+
+func runREPL(renderer *entity.Renderer, isJson bool, where entity.Where) {
+	r := &repl{
+		editor:   lineedit.New(os.Stdin, color.Output),
+		renderer: renderer,
+		where:    where,
+		json:     isJson,
+	}
+	r.editor.Prompt = "nabili> "
+	r.editor.Complete = r.complete
+	if err := r.editor.LoadHistory(historyFile()); err != nil {
+		log.Println("历史记录读取失败:", err)
+	}
+
+	for {
+		line, err := r.editor.ReadLine()
+		if errors.Is(err, lineedit.ErrInterrupt) {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Println(err)
+			}
+			return
+		}
+		if err := r.editor.AddHistory(line); err != nil {
+			log.Println("历史记录保存失败:", err)
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case line == "quit" || line == "exit":
+			return
+		case strings.HasPrefix(line, ":"):
+			if !r.command(line) {
+				return
+			}
+		default:
+			r.query(line)
+		}
+	}
+}
+
+func historyFile() string {
+	return filepath.Join(constant.DataDirPath, "history")
+}
+
+// command runs a meta command and reports whether to go on
+func (r *repl) command(line string) bool {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	arg := strings.Join(args, " ")
+
+	switch name {
+	case ":quit", ":exit", ":q":
+		return false
+	case ":help", ":h", ":?":
+		fmt.Fprintln(color.Output, replHelp)
+	case ":db4", ":db6":
+		typ, label := dbif.QueryType(dbif.TypeIPv4), "IPv4"
+		if name == ":db6" {
+			typ, label = dbif.TypeIPv6, "IPv6"
+		}
+		if arg == "" {
+			fmt.Fprintf(color.Output, "%s 数据库: %s\n", label, db.Selected(typ))
+			break
+		}
+		if err := db.Select(typ, arg); err != nil {
+			fmt.Fprintln(color.Output, err)
+			break
+		}
+		fmt.Fprintf(color.Output, "%s 数据库已切换为 %s\n", label, arg)
+	case ":compare":
+		switch arg {
+		case "":
+		case "off":
+			r.compare = nil
+		default:
+			names, err := parseCompare(arg)
+			if err != nil {
+				fmt.Fprintln(color.Output, err)
+				return true
+			}
+			r.compare = names
+		}
+		if len(r.compare) == 0 {
+			fmt.Fprintln(color.Output, "对比: 关闭")
+		} else {
+			fmt.Fprintln(color.Output, "对比:", strings.Join(r.compare, ", "))
+		}
+	case ":json":
+		switch strings.ToLower(arg) {
+		case "":
+			r.json = !r.json
+		case "on", "true", "1":
+			r.json = true
+		case "off", "false", "0":
+			r.json = false
+		default:
+			fmt.Fprintf(color.Output, "未知的参数 %s，可选 on, off\n", arg)
+			return true
+		}
+		fmt.Fprintln(color.Output, "JSON 输出:", onOff(r.json))
+	case ":info":
+		r.info()
+	default:
+		fmt.Fprintf(color.Output, "未知的命令 %s，输入 :help 查看帮助\n", name)
+	}
+	return true
+}
+
+// parseCompare parses comma separated database names
+func parseCompare(arg string) ([]string, error) {
+	known := make(map[string]bool)
+	for _, name := range ipDBNames() {
+		known[name] = true
+	}
+	var names []string
+	for _, name := range strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !known[name] {
+			return nil, fmt.Errorf("数据库 %s 不存在或不支持 IP 查询", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (r *repl) info() {
+	compare := "关闭"
+	if len(r.compare) > 0 {
+		compare = strings.Join(r.compare, ", ")
+	}
+	fmt.Fprintln(color.Output, "Nabili Version:  ", constant.Version)
+	fmt.Fprintln(color.Output, "DB Data Dir Path:", constant.DataDirPath)
+	fmt.Fprintln(color.Output, "History File:    ", historyFile())
+	fmt.Fprintln(color.Output, "IPv4 DB:         ", db.Selected(dbif.TypeIPv4))
+	fmt.Fprintln(color.Output, "IPv6 DB:         ", db.Selected(dbif.TypeIPv6))
+	fmt.Fprintln(color.Output, "CDN DB:          ", db.Selected(dbif.TypeDomain))
+	fmt.Fprintln(color.Output, "Annotate:        ", r.renderer.Mode)
+	fmt.Fprintln(color.Output, "Compare:         ", compare)
+	fmt.Fprintln(color.Output, "JSON:            ", onOff(r.json))
+}
+
+// query annotates a line, with the results of the compared databases below
+func (r *repl) query(line string) {
+	es := entity.ParseLine(line)
+	if len(r.where) > 0 && !r.where.Match(es) {
+		return
+	}
+	if r.json {
+		fmt.Fprint(color.Output, es.Json())
+	} else {
+		fmt.Fprintln(color.Output, r.renderer.Render(es))
+	}
+	if len(r.compare) == 0 {
+		return
+	}
+
+	type compared struct {
+		Source string `json:"source"`
+		Text   string `json:"text,omitempty"`
+		Error  string `json:"error,omitempty"`
+	}
+	width := 0
+	for _, name := range r.compare {
+		if w := common.DisplayWidth(name); w > width {
+			width = w
+		}
+	}
+	for _, e := range es {
+		if e.Type != entity.TypeIPv4 && e.Type != entity.TypeIPv6 {
+			continue
+		}
+		var results []compared
+		for _, name := range r.compare {
+			c := compared{Source: name}
+			res, err := db.FindIn(name, dbif.QueryType(e.Type), e.Text)
+			if err != nil {
+				c.Error = err.Error()
+			} else {
+				c.Text = res.String()
+			}
+			results = append(results, c)
+		}
+
+		if r.json {
+			data, _ := json.Marshal(struct {
+				IP      string     `json:"ip"`
+				Compare []compared `json:"compare"`
+			}{e.Text, results})
+			fmt.Fprintln(color.Output, string(data))
+			continue
+		}
+		fmt.Fprintln(color.Output, e.Text)
+		for _, c := range results {
+			text := color.RedString(c.Text)
+			if c.Error != "" {
+				text = c.Error
+			}
+			fmt.Fprintf(color.Output, "  %-*s  %s\n", width, c.Source, text)
+		}
+	}
+}
+
+// complete completes the meta commands and their arguments
+func (r *repl) complete(head string) []string {
+	if head != "" && !strings.HasPrefix(head, ":") {
+		return nil
+	}
+	name, arg, hasArg := strings.Cut(head, " ")
+	if !hasArg {
+		return withPrefix("", replCommands, name)
+	}
+
+	var words []string
+	switch name {
+	case ":db4":
+		words = db.Names(dbif.TypeIPv4)
+	case ":db6":
+		words = db.Names(dbif.TypeIPv6)
+	case ":compare":
+		words = append(ipDBNames(), "off")
+	case ":json":
+		words = []string{"on", "off"}
+	}
+	prefix := name + " "
+	if name == ":compare" {
+		if i := strings.LastIndexAny(arg, ", "); i >= 0 {
+			prefix, arg = prefix+arg[:i+1], arg[i+1:]
+		}
+	}
+	return withPrefix(prefix, words, arg)
+}
+
+func withPrefix(prefix string, words []string, partial string) []string {
+	var candidates []string
+	for _, w := range words {
+		if strings.HasPrefix(w, partial) {
+			candidates = append(candidates, prefix+w)
+		}
+	}
+	return candidates
+}
+
+// ipDBNames are the names of the IPv4 and IPv6 databases
+func ipDBNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, typ := range []dbif.QueryType{dbif.TypeIPv4, dbif.TypeIPv6} {
+		for _, name := range db.Names(typ) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
@@ -1,20 +1,21 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
 
 	"github.com/abc1763613206/nabili/internal/constant"
 	"github.com/abc1763613206/nabili/internal/db"
-	"github.com/abc1763613206/nabili/pkg/common"
+	"github.com/abc1763613206/nabili/pkg/charset"
 	"github.com/abc1763613206/nabili/pkg/entity"
 )
 
@@ -40,9 +41,12 @@ Find document on: https://github.com/abc1763613206/nabili
 #3 Interactive query
 
 	$ nabili
-	123.23.23.23
+	nabili> 123.23.23.23
 	123.23.23.23 [越南 越南邮电集团公司]
-	quit
+	nabili> :compare qqwry,geoip
+	nabili> :db4 ip2region
+	nabili> :help
+	nabili> quit
 
 #4 Use with dig
 
@@ -72,11 +76,26 @@ Find document on: https://github.com/abc1763613206/nabili
 	$ nabili -6 iqiyi 240e:b1:a810:2011::a1
 	$ nabili -4 baidu 8.8.8.8
 	$ nabili -6 baidu 240e:b1:a810:2011::a1
+
+#9 Choose what gets annotated
+
+	$ netstat -tn | nabili --only ipv4 --skip-private
+	$ cat access.log | nabili --exclude-cidr 10.0.0.0/8,192.0.2.0/24
+	$ cat access.log | nabili --where country=中国 --where isp=电信
+
+#10 Keep tables aligned
+
+	$ ss -tn | nabili --annotate column
+	$ netstat -tn | nabili --annotate eol
+
+#11 Logs in other encodings
+
+	$ cat gbk.log | nabili --encoding gbk
+	$ type iis.log | nabili --output-encoding auto
 `,
 	Version: constant.Version,
 	Args:    cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
-		gbk, _ := cmd.Flags().GetBool("gbk")
 		isJson, _ := cmd.Flags().GetBool("json")
 		ipv4DB, _ := cmd.Flags().GetString("db4")
 		ipv6DB, _ := cmd.Flags().GetString("db6")
@@ -89,35 +108,131 @@ Find document on: https://github.com/abc1763613206/nabili
 			db.CmdIPv6DB = ipv6DB
 		}
 
+		where, err := setFilter(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		annotate, _ := cmd.Flags().GetString("annotate")
+		mode, err := entity.ParseAnnotateMode(annotate)
+		if err != nil {
+			log.Fatalln("--annotate:", err)
+		}
+		renderer := &entity.Renderer{Mode: mode}
+		enc, err := inputEncoding(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
 		if len(args) == 0 {
-			stdin := bufio.NewScanner(os.Stdin)
-			stdin.Split(common.ScanLines)
+			if (enc == nil || enc == unicode.UTF8) && (isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())) {
+				runREPL(renderer, isJson, where)
+				return
+			}
+			stdin := charset.NewScanner(os.Stdin, enc)
+			out, err := outputWriter(cmd, color.Output, stdin)
+			if err != nil {
+				log.Fatalln(err)
+			}
 			for stdin.Scan() {
 				line := stdin.Text()
-				if gbk {
-					line, _, _ = transform.String(simplifiedchinese.GBK.NewDecoder(), line)
-				}
 				if line := strings.TrimSpace(line); line == "quit" || line == "exit" {
 					return
 				}
+				es := entity.ParseLine(line)
+				if len(where) > 0 && !where.Match(es) {
+					continue
+				}
 				if isJson {
-					_, _ = fmt.Fprintf(color.Output, "%s", entity.ParseLine(line).Json())
+					_, _ = fmt.Fprintf(out, "%s", es.Json())
 				} else {
-					_, _ = fmt.Fprintf(color.Output, "%s", entity.ParseLine(line).ColorString())
+					_, _ = fmt.Fprintf(out, "%s", renderer.Render(es))
 				}
 			}
 		} else {
+			out, err := outputWriter(cmd, color.Output, nil)
+			if err != nil {
+				log.Fatalln(err)
+			}
 			if isJson {
-				_, _ = fmt.Fprintf(color.Output, "%s", entity.ParseLine(strings.Join(args, " ")).Json())
+				es := entity.ParseLine(strings.Join(args, " "))
+				if len(where) == 0 || where.Match(es) {
+					_, _ = fmt.Fprintf(out, "%s", es.Json())
+				}
 			} else {
 				for _, line := range args {
-					_, _ = fmt.Fprintf(color.Output, "%s\n", entity.ParseLine(line).ColorString())
+					es := entity.ParseLine(line)
+					if len(where) > 0 && !where.Match(es) {
+						continue
+					}
+					_, _ = fmt.Fprintf(out, "%s\n", renderer.Render(es))
 				}
 			}
 		}
 	},
 }
 
+// setFilter sets entity.CmdFilter from the --only, --skip-private and --exclude-cidr flags
+// and returns the conditions of --where
+func setFilter(cmd *cobra.Command) (entity.Where, error) {
+	only, _ := cmd.Flags().GetStringSlice("only")
+	skipPrivate, _ := cmd.Flags().GetBool("skip-private")
+	excludeCIDRs, _ := cmd.Flags().GetStringSlice("exclude-cidr")
+	conditions, _ := cmd.Flags().GetStringArray("where")
+
+	types, err := entity.ParseTypes(only)
+	if err != nil {
+		return nil, fmt.Errorf("--only: %v", err)
+	}
+	prefixes, err := entity.ParseCIDRs(excludeCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("--exclude-cidr: %v", err)
+	}
+	entity.CmdFilter = entity.Filter{Types: types, SkipPrivate: skipPrivate, ExcludeCIDRs: prefixes}
+
+	where, err := entity.ParseWhere(conditions)
+	if err != nil {
+		return nil, fmt.Errorf("--where: %v", err)
+	}
+	return where, nil
+}
+
+// addEncodingFlags adds --encoding with its alias --gbk, and --output-encoding if output is set
+func addEncodingFlags(cmd *cobra.Command, output bool) {
+	names := strings.Join(charset.Names, ", ")
+	cmd.Flags().String("encoding", charset.Auto, "Encoding of the input ("+names+"), auto detects it by the byte order mark or line by line")
+	cmd.Flags().Bool("gbk", false, "Alias of --encoding gbk")
+	if output {
+		cmd.Flags().String("output-encoding", "utf-8", "Encoding of the output ("+names+"), auto uses the encoding of the input")
+	}
+}
+
+// inputEncoding returns the encoding of --encoding, nil if it is detected
+func inputEncoding(cmd *cobra.Command) (encoding.Encoding, error) {
+	name, _ := cmd.Flags().GetString("encoding")
+	if gbk, _ := cmd.Flags().GetBool("gbk"); gbk {
+		name = "gbk"
+	}
+	enc, err := charset.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("--encoding: %v", err)
+	}
+	return enc, nil
+}
+
+// outputWriter returns w encoding to --output-encoding, for auto in the encoding
+// of the lines read by input, or UTF-8 without input
+func outputWriter(cmd *cobra.Command, w io.Writer, input *charset.Scanner) (io.Writer, error) {
+	name, _ := cmd.Flags().GetString("output-encoding")
+	enc, err := charset.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("--output-encoding: %v", err)
+	}
+	if enc == nil && input != nil {
+		return charset.NewFuncWriter(w, input.LineEncoding), nil
+	}
+	return charset.NewWriter(w, enc), nil
+}
+
 // Execute parse subcommand and run
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -126,8 +241,13 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.Flags().Bool("gbk", false, "Use GBK decoder")
+	addEncodingFlags(rootCmd, true)
 	rootCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
 	rootCmd.Flags().StringP("db4", "4", "", "IPv4 database provider (qqwry, geoip, ip2region, dbip, ipip, ip2location, bili, ipsb, iqiyi, baidu)")
 	rootCmd.Flags().StringP("db6", "6", "", "IPv6 database provider (zxipv6wry, geoip, dbip, ipip, ip2location, bili, ipsb, iqiyi, baidu)")
+	rootCmd.Flags().StringSlice("only", nil, "Only annotate these entity types (ipv4, ipv6, ip, domain)")
+	rootCmd.Flags().Bool("skip-private", false, "Do not annotate private, loopback, link-local and other local addresses")
+	rootCmd.Flags().StringSlice("exclude-cidr", nil, "Do not annotate addresses in these CIDRs")
+	rootCmd.Flags().String("annotate", string(entity.AnnotateInline), "Where to put the annotations: inline, replace (the IP), eol (end of line) or column (aligned tables)")
+	rootCmd.Flags().StringArray("where", nil, "Only output lines with an entity matching all field=value conditions, e.g. country=中国")
 }
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/internal/db"
+	"github.com/abc1763613206/nabili/pkg/charset"
+	"github.com/abc1763613206/nabili/pkg/dbif"
+	"github.com/abc1763613206/nabili/pkg/trace"
+)
+
+// traceCmd represents the trace command
+var traceCmd = &cobra.Command{
+	Use:   "trace [file]",
+	Short: "summarize the output of traceroute, mtr, tracepath or tracert per hop",
+	Long: `summarize the output of traceroute, mtr --report, tracepath or Windows tracert per hop.
+
+The trace is read from the file or stdin and printed as a table with the loss,
+the average round trip time, the ASN, the location and the ISP of every hop.
+A line is printed where the route enters another country or autonomous system.
+
+The encoding of the input is detected, so tracert output saved on a Chinese
+Windows host in GBK or UTF-16 can be read as is.
+
+The ASN is taken from traceroute -A or mtr -z output, otherwise from the asn
+database if it is downloaded by "nabili update --db asn".`,
+	Example: "traceroute 1.1.1.1 | nabili trace\nmtr --report -z 8.8.8.8 | nabili trace\ntracepath -b example.com | nabili trace --json\nnabili trace tracert.txt",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		isJson, _ := cmd.Flags().GetBool("json")
+		if ipv4DB, _ := cmd.Flags().GetString("db4"); ipv4DB != "" {
+			db.CmdIPv4DB = ipv4DB
+		}
+		if ipv6DB, _ := cmd.Flags().GetString("db6"); ipv6DB != "" {
+			db.CmdIPv6DB = ipv6DB
+		}
+
+		var r io.Reader = os.Stdin
+		if len(args) > 0 {
+			f, err := os.Open(constant.WorkPath(args[0]))
+			if err != nil {
+				log.Fatalln(err)
+			}
+			defer f.Close()
+			r = f
+		}
+		enc, err := inputEncoding(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		scanner := charset.NewScanner(r, enc)
+		out, err := outputWriter(cmd, color.Output, scanner)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		p := &trace.Parser{}
+		table := trace.NewTable(out)
+		add := func(h *trace.Hop) {
+			if h == nil {
+				return
+			}
+			annotateHop(h)
+			if len(p.Hops) == 0 && !isJson {
+				_ = table.WriteHeader()
+			}
+			p.Add(h)
+			if !isJson {
+				_ = table.Write(h)
+			}
+		}
+
+		for scanner.Scan() {
+			add(p.Parse(scanner.Text()))
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalln(err)
+		}
+		add(p.Flush())
+
+		if len(p.Hops) == 0 {
+			log.Fatalln("没有识别到 traceroute、mtr、tracepath 或 tracert 的输出")
+		}
+		if isJson {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(p.Trace); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	},
+}
+
+// annotateHop looks up the location and the autonomous system of the replies
+func annotateHop(h *trace.Hop) {
+	for _, r := range h.Replies {
+		if !r.IP.IsValid() {
+			continue
+		}
+		ip := r.IP.Unmap()
+		typ := dbif.QueryType(dbif.TypeIPv6)
+		if ip.Is4() {
+			typ = dbif.TypeIPv4
+		}
+		if res := db.Find(typ, ip.String()); res != nil {
+			r.Info = res.String()
+			r.Source = res.Source
+			r.Class = res.Class
+			r.Fields = res.Fields()
+		}
+		if r.Class != "" {
+			continue
+		}
+		if asn, found := db.FindASN(ip); found {
+			if r.ASN == 0 {
+				r.ASN = asn.Number
+			}
+			if r.ASN == asn.Number {
+				r.ASOrg = asn.Organization
+			}
+		}
+	}
+}
+
+func init() {
+	addEncodingFlags(traceCmd, true)
+	traceCmd.Flags().BoolP("json", "j", false, "Output in JSON format")
+	traceCmd.Flags().StringP("db4", "4", "", "IPv4 database provider (qqwry, geoip, ip2region, dbip, ipip, ip2location, bili, ipsb, iqiyi, baidu)")
+	traceCmd.Flags().StringP("db6", "6", "", "IPv6 database provider (zxipv6wry, geoip, dbip, ipip, ip2location, bili, ipsb, iqiyi, baidu)")
+	rootCmd.AddCommand(traceCmd)
+}
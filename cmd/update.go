@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"log"
+	"os"
 	"strings"
 
 	"github.com/abc1763613206/nabili/internal/db"
@@ -12,12 +13,20 @@ import (
 
 // updateCmd represents the update command
 var updateCmd = &cobra.Command{
-	Use:     "update [--db dbs -v]",
-	Short:   "update qqwry, zxipv6wry, ip2region ip database and cdn, update nabili to latest version if -v",
-	Long:    `update qqwry, zxipv6wry, ip2region ip database and cdn. Use commas to separate. update nabili to latest version if -v`,
-	Example: "nabili update --db qqwry,cdn -v",
+	Use:   "update [--db dbs -v]",
+	Short: "update qqwry, zxipv6wry, ip2region ip database, cdn and public suffix list, update nabili to latest version if -v",
+	Long: `update qqwry, zxipv6wry, ip2region ip database, cdn and public suffix list. Use commas to separate. update nabili to latest version if -v
+
+geoip, dbip, asn and ip2location can be updated when specified with --db,
+geoip needs maxmind.account-id and maxmind.license-key in config or NALI_MAXMIND_ACCOUNT_ID and NALI_MAXMIND_LICENSE_KEY,
+ip2location needs ip2location.token in config or NALI_IP2LOCATION_TOKEN
+
+--mirror pulls databases from an extracted "nabili db export" bundle on a local dir or web server`,
+	Example: "nabili update --db qqwry,cdn -v\nnabili update --db geoip,dbip,ip2location\nnabili update --mirror file:///mnt/nabili\nnabili update --mirror http://internal/nabili/",
 	Run: func(cmd *cobra.Command, args []string) {
 		DBs, _ := cmd.Flags().GetString("db")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		mirror, _ := cmd.Flags().GetString("mirror")
 
 		version, _ := cmd.Flags().GetBool("v")
 		if version {
@@ -30,12 +39,17 @@ var updateCmd = &cobra.Command{
 		if DBs != "" {
 			DBNameArray = strings.Split(DBs, ",")
 		}
-		db.UpdateDB(DBNameArray...)
+		if err := db.UpdateDB(db.UpdateOptions{Parallel: parallel, Mirror: mirror}, DBNameArray...); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
 	updateCmd.PersistentFlags().String("db", "", "choose db you want to update")
 	updateCmd.PersistentFlags().Bool("v", false, "decide whether to update the nabili version")
+	updateCmd.PersistentFlags().String("mirror", "", "file:// or http(s):// url of an extracted bundle to update from")
+	updateCmd.PersistentFlags().Int("parallel", db.DefaultUpdateParallel, "max number of databases downloaded at the same time")
 	rootCmd.AddCommand(updateCmd)
 }
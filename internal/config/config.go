@@ -13,6 +13,7 @@ func ReadConfig(basePath string) {
 	viper.SetDefault("selected.ipv4", "qqwry")
 	viper.SetDefault("selected.ipv6", "zxipv6wry")
 	viper.SetDefault("selected.cdn", "cdn")
+	viper.SetDefault("selected.overlay", "")
 	viper.SetDefault("selected.lang", "zh-CN")
 
 	viper.SetConfigName("config")
@@ -29,7 +30,13 @@ func ReadConfig(basePath string) {
 	_ = viper.BindEnv("selected.ipv4", "NALI_DB_IP4")
 	_ = viper.BindEnv("selected.ipv6", "NALI_DB_IP6")
 	_ = viper.BindEnv("selected.cdn", "NALI_DB_CDN")
+	_ = viper.BindEnv("selected.overlay", "NALI_DB_OVERLAY")
+	_ = viper.BindEnv("nat64.prefixes", "NALI_NAT64_PREFIXES")
 	_ = viper.BindEnv("selected.lang", "NALI_LANG")
+	_ = viper.BindEnv("maxmind.account-id", "NALI_MAXMIND_ACCOUNT_ID")
+	_ = viper.BindEnv("maxmind.license-key", "NALI_MAXMIND_LICENSE_KEY")
+	_ = viper.BindEnv("ip2location.token", "NALI_IP2LOCATION_TOKEN")
+	_ = viper.BindEnv("ip2location.file-code", "NALI_IP2LOCATION_FILE_CODE")
 
 	// Auto-migrate remote sources to existing configurations
 	migration.AutoMigrateRemoteSources()
@@ -11,6 +11,8 @@ import (
 var (
 	ConfigDirPath string
 	DataDirPath   string
+	// WorkDirPath is the working dir before changing to DataDirPath
+	WorkDirPath string
 )
 
 func init() {
@@ -32,9 +34,18 @@ func init() {
 	prepareDir(ConfigDirPath)
 	prepareDir(DataDirPath)
 
+	WorkDirPath, _ = os.Getwd()
 	_ = os.Chdir(DataDirPath)
 }
 
+// WorkPath resolves a command line path against WorkDirPath
+func WorkPath(path string) string {
+	if filepath.IsAbs(path) || WorkDirPath == "" {
+		return path
+	}
+	return filepath.Join(WorkDirPath, path)
+}
+
 func prepareDir(dir string) {
 	stat, err := os.Stat(dir)
 	if os.IsNotExist(err) {
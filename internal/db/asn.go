@@ -0,0 +1,40 @@
+package db
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"sync"
+
+	"github.com/abc1763613206/nabili/pkg/geoip"
+)
+
+var (
+	asnReader     *geoip.ASNReader
+	asnReaderOnce sync.Once
+)
+
+// FindASN looks up the autonomous system of ip in the asn database,
+// found is false if the database is not downloaded or ip is not announced
+func FindASN(ip netip.Addr) (asn geoip.ASN, found bool) {
+	asnReaderOnce.Do(func() {
+		file := getDbByName("asn").File
+		if _, err := os.Stat(file); err != nil {
+			return
+		}
+		r, err := geoip.NewASNReader(file)
+		if err != nil {
+			log.Printf("ASN 数据库 %s 读取失败: %v\n", file, err)
+			return
+		}
+		asnReader = r
+	})
+	if asnReader == nil {
+		return geoip.ASN{}, false
+	}
+	asn, found, err := asnReader.Lookup(ip.Unmap())
+	if err != nil {
+		return geoip.ASN{}, false
+	}
+	return asn, found
+}
@@ -0,0 +1,52 @@
+package db_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/abc1763613206/nabili/internal/db"
+	"github.com/abc1763613206/nabili/pkg/dbif"
+	"github.com/abc1763613206/nabili/pkg/entity"
+)
+
+// failDB fails the test if a query reaches it
+type failDB struct {
+	t *testing.T
+}
+
+func (d failDB) Find(query string, params ...string) (fmt.Stringer, error) {
+	d.t.Errorf("%s was sent to the database", query)
+	return nil, errors.New("unexpected query")
+}
+
+func (d failDB) Name() string {
+	return "fail"
+}
+
+func TestBogonNotSentToDB(t *testing.T) {
+	defer db.SetDB(dbif.TypeIPv4, failDB{t})()
+	defer db.SetDB(dbif.TypeIPv6, failDB{t})()
+
+	for _, tt := range []struct {
+		typ          dbif.QueryType
+		query, class string
+	}{
+		{dbif.TypeIPv4, "10.0.0.1", "private"},
+		{dbif.TypeIPv4, "100.64.0.1", "shared"},
+		{dbif.TypeIPv6, "fe80::1", "link-local"},
+	} {
+		res := db.Find(tt.typ, tt.query)
+		if res == nil || res.Source != "bogon" || res.Class != tt.class {
+			t.Errorf("Find(%s) = %+v, want class %s from bogon", tt.query, res, tt.class)
+			continue
+		}
+
+		es := entity.ParseLine(tt.query)
+		want := fmt.Sprintf(`"class":"%s"`, tt.class)
+		if len(es) != 1 || !strings.Contains(es[0].Json(), want) {
+			t.Errorf("ParseLine(%s) = %s, want it to contain %s", tt.query, es.Json(), want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/pkg/nbdb"
+)
+
+type BuildOptions struct {
+	// Sources are the databases to merge, earlier sources win by default
+	Sources []string
+	// Precedence overrides the sources of a field, e.g. city -> geoip,ip2region
+	Precedence map[string][]string
+}
+
+// Build merges the ranges of several databases into a nbdb file
+func Build(output string, opts BuildOptions) error {
+	if len(opts.Sources) == 0 {
+		return fmt.Errorf("至少需要一个数据库")
+	}
+
+	builder := nbdb.NewBuilder()
+	for _, name := range opts.Sources {
+		adb, src, err := openIterable(name)
+		if err != nil {
+			return err
+		}
+		log.Printf("正在读取 %s\n", adb.Name)
+		if err := builder.Add(adb.Name, src); err != nil {
+			return err
+		}
+	}
+
+	for field, names := range opts.Precedence {
+		sources := make([]string, 0, len(names))
+		for _, name := range names {
+			if adb, found := NameDBMap[name]; found {
+				name = adb.Name
+			} else {
+				name = constant.WorkPath(name)
+			}
+			sources = append(sources, name)
+		}
+		if err := builder.SetPrecedence(field, sources...); err != nil {
+			return err
+		}
+	}
+
+	ranges := builder.Build()
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := nbdb.Write(f, ranges, builder.Sources()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("已生成 %s，共 %d 个地址段\n", output, len(ranges))
+	return nil
+}
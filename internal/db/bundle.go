@@ -0,0 +1,286 @@
+package db
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+// ManifestName is the manifest file in a bundle or a mirror dir
+const ManifestName = "manifest.json"
+
+const manifestVersion = 1
+
+var configMu sync.Mutex
+
+// Manifest describes the databases in a bundle
+type Manifest struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Databases []ManifestEntry `json:"databases"`
+}
+
+type ManifestEntry struct {
+	Name      string    `json:"name"`
+	Format    Format    `json:"format"`
+	File      string    `json:"file"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Source    string    `json:"source,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func (m *Manifest) find(name string) *ManifestEntry {
+	for i := range m.Databases {
+		if m.Databases[i].Name == name {
+			return &m.Databases[i]
+		}
+	}
+	return nil
+}
+
+// checkFile rejects file names that are not a plain name in the data dir,
+// a crafted bundle or mirror could write anywhere otherwise
+func (e *ManifestEntry) checkFile() error {
+	if e.File == "" || e.File == "." || e.File == ".." || filepath.IsAbs(e.File) || filepath.Base(e.File) != e.File {
+		return fmt.Errorf("%s 的文件名 %q 无效", e.Name, e.File)
+	}
+	return nil
+}
+
+// Verify checks the size and SHA-256 of data against the entry and validates the format
+func (e *ManifestEntry) Verify(data []byte) error {
+	if int64(len(data)) != e.Size {
+		return fmt.Errorf("%s 大小不匹配: %d != %d", e.Name, len(data), e.Size)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != e.SHA256 {
+		return fmt.Errorf("%s SHA-256 校验失败", e.Name)
+	}
+	if !CheckFormat(e.Format, data) {
+		return fmt.Errorf("%s 不是有效的 %s 数据库", e.Name, e.Format)
+	}
+	return nil
+}
+
+// ExportBundle packs the local files of the given databases, or of all configured
+// databases, with a manifest into a tar.gz bundle
+func ExportBundle(bundlePath string, dbNames ...string) error {
+	var dbs List
+	if len(dbNames) == 0 {
+		dbs = configuredDBList()
+	} else {
+		for _, name := range dbNames {
+			adb := findDbByName(strings.TrimSpace(name), true)
+			if adb == nil {
+				return fmt.Errorf("数据库 %s 不存在", name)
+			}
+			dbs = append(dbs, adb)
+		}
+	}
+
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	manifest := Manifest{Version: manifestVersion, CreatedAt: time.Now().UTC()}
+	var files [][]byte
+	for _, adb := range dbs {
+		if adb.File == "" || adb.Format == FormatRemote {
+			continue
+		}
+		filePath := dataFilePath(adb.File)
+		stat, err := os.Stat(filePath)
+		if err != nil {
+			log.Printf("跳过 %s: %v\n", adb.Name, err)
+			continue
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		entry := ManifestEntry{
+			Name:      adb.Name,
+			Format:    adb.Format,
+			File:      filepath.Base(adb.File),
+			Size:      int64(len(data)),
+			SHA256:    hex.EncodeToString(sum[:]),
+			FetchedAt: stat.ModTime().UTC(),
+		}
+		if len(adb.DownloadUrls) > 0 {
+			entry.Source = adb.DownloadUrls[0]
+		}
+		manifest.Databases = append(manifest.Databases, entry)
+		files = append(files, data)
+	}
+	if len(manifest.Databases) == 0 {
+		return errors.New("没有可导出的数据库文件")
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, ManifestName, manifestData, manifest.CreatedAt); err != nil {
+		return err
+	}
+	for i, entry := range manifest.Databases {
+		if err := writeTarFile(tw, entry.File, files[i], entry.FetchedAt); err != nil {
+			return err
+		}
+		log.Printf("已导出 %s: %s (%s)\n", entry.Name, entry.File, entry.SHA256[:12])
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+// ImportBundle verifies every file of a bundle against its manifest and then installs them,
+// nothing is installed if any file fails verification
+func ImportBundle(bundlePath string) error {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("cannot decompress gzip file: %v", err)
+	}
+
+	var manifest *Manifest
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("cannot decompress tar file: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		name := path.Clean(hdr.Name)
+		if name == ManifestName {
+			manifest = &Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return fmt.Errorf("manifest 解析失败: %v", err)
+			}
+		} else {
+			files[name] = data
+		}
+	}
+	if manifest == nil {
+		return errors.New("bundle 中缺少 " + ManifestName)
+	}
+
+	for _, entry := range manifest.Databases {
+		if err := entry.checkFile(); err != nil {
+			return err
+		}
+		data, found := files[entry.File]
+		if !found {
+			return fmt.Errorf("bundle 中缺少 %s 的文件 %s", entry.Name, entry.File)
+		}
+		if err := entry.Verify(data); err != nil {
+			return err
+		}
+	}
+	for i := range manifest.Databases {
+		entry := &manifest.Databases[i]
+		if err := installBundleEntry(entry, files[entry.File]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installBundleEntry saves a verified file as the database of the same name,
+// the database is added to the config if it does not exist yet
+func installBundleEntry(entry *ManifestEntry, data []byte) error {
+	if err := entry.checkFile(); err != nil {
+		return err
+	}
+	// mirror updates install concurrently
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	adb, found := NameDBMap[entry.Name]
+	if !found {
+		adb = &DB{
+			Name:      entry.Name,
+			Format:    entry.Format,
+			File:      entry.File,
+			Languages: formatLanguages[entry.Format],
+			Types:     formatTypes[entry.Format],
+		}
+		if entry.Source != "" {
+			adb.DownloadUrls = []string{entry.Source}
+		}
+		if err := registerDB(adb); err != nil {
+			return err
+		}
+	} else if adb.Format != entry.Format {
+		return fmt.Errorf("数据库 %s 的格式为 %s，但 bundle 中为 %s 格式", adb.Name, adb.Format, entry.Format)
+	}
+
+	filePath := dataFilePath(adb.File)
+	if err := common.SaveFile(filePath, data); err != nil {
+		return err
+	}
+	if !entry.FetchedAt.IsZero() {
+		_ = os.Chtimes(filePath, entry.FetchedAt, entry.FetchedAt)
+	}
+	log.Printf("已安装 %s: %s\n", adb.Name, filePath)
+	return nil
+}
+
+func dataFilePath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(constant.DataDirPath, file)
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+)
+
+// writeBundle writes a bundle with a manifest of the entries and a file for each of them
+func writeBundle(t *testing.T, bundlePath string, entries []ManifestEntry, data []byte) {
+	t.Helper()
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	manifest, _ := json.Marshal(Manifest{Version: manifestVersion, Databases: entries})
+	if err := writeTarFile(tw, ManifestName, manifest, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err := writeTarFile(tw, entry.File, data, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHostileManifest(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	if err := os.Mkdir(dataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	savedDataDir := constant.DataDirPath
+	constant.DataDirPath = dataDir
+	defer func() { constant.DataDirPath = savedDataDir }()
+
+	data := []byte("not a database")
+	sum := sha256.Sum256(data)
+	for _, file := range []string{
+		"../evil.dat",
+		"sub/../../evil.dat",
+		filepath.Join(dir, "evil.dat"),
+		"sub/evil.dat",
+		"..",
+		"",
+	} {
+		entry := ManifestEntry{
+			Name:   "evil",
+			Format: FormatQQWry,
+			File:   file,
+			Size:   int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+
+		bundlePath := filepath.Join(dir, "bundle.tar.gz")
+		writeBundle(t, bundlePath, []ManifestEntry{entry}, data)
+		if err := ImportBundle(bundlePath); err == nil || !strings.Contains(err.Error(), "无效") {
+			t.Errorf("ImportBundle with file %q: err = %v", file, err)
+		}
+
+		if err := installBundleEntry(&entry, data); err == nil {
+			t.Errorf("installBundleEntry with file %q should fail", file)
+		}
+
+		update, _ := getMirrorUpdateFunc("http://127.0.0.1:1", &Manifest{Databases: []ManifestEntry{entry}}, "evil")
+		if err := update(nil); err == nil || !strings.Contains(err.Error(), "无效") {
+			t.Errorf("mirror update with file %q: err = %v", file, err)
+		}
+
+		for _, p := range []string{filepath.Join(dir, "evil.dat"), filepath.Join(dataDir, "sub", "evil.dat")} {
+			if _, err := os.Stat(p); err == nil {
+				t.Fatalf("file %q was written to %s", file, p)
+			}
+		}
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	dir := setupImport(t, List{
+		{Name: "test-qqwry", Format: FormatQQWry, File: "qqwry.dat", Types: TypesIPv4},
+		{Name: "test-city", Format: FormatMMDB, File: "GeoLite2-City.mmdb", Types: TypesIP},
+		{Name: "test-missing", Format: FormatZXIPv6Wry, File: "zx.db", Types: TypesIPv6},
+	})
+	files := testDatabases(t)
+	for name, format := range map[string]Format{"qqwry.dat": FormatQQWry, "GeoLite2-City.mmdb": FormatMMDB} {
+		if err := os.WriteFile(dataFilePath(name), files[format], 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bundlePath := filepath.Join(dir, "bundle.tar.gz")
+	if err := ExportBundle(bundlePath, "test-qqwry", "unknown"); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("export of an unknown database: err = %v", err)
+	}
+	if _, err := os.Stat(bundlePath); err == nil {
+		t.Error("a bundle was written for an unknown database")
+	}
+	// missing files are skipped
+	if err := ExportBundle(bundlePath); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"qqwry.dat", "GeoLite2-City.mmdb"} {
+		if err := os.WriteFile(dataFilePath(name), []byte("replaced"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the bundle registers databases missing from the config
+	delete(NameDBMap, "test-city")
+	viper.Set("databases", List{{Name: "test-qqwry", Format: FormatQQWry, File: "qqwry.dat", Types: TypesIPv4}})
+	if err := ImportBundle(bundlePath); err != nil {
+		t.Fatal(err)
+	}
+	for name, format := range map[string]Format{"qqwry.dat": FormatQQWry, "GeoLite2-City.mmdb": FormatMMDB} {
+		if data, _ := os.ReadFile(dataFilePath(name)); !bytes.Equal(data, files[format]) {
+			t.Errorf("%s was not restored", name)
+		}
+	}
+	if _, err := os.Stat(dataFilePath("zx.db")); err == nil {
+		t.Error("the missing database was installed")
+	}
+	if adb := NameDBMap["test-city"]; adb == nil || adb.Format != FormatMMDB || adb.File != "GeoLite2-City.mmdb" {
+		t.Errorf("test-city registered as %+v", adb)
+	}
+}
+
+func TestMirrorUpdate(t *testing.T) {
+	dir := setupImport(t, List{
+		{Name: "test-good", Format: FormatQQWry, File: "good.dat", Types: TypesIPv4},
+		{Name: "test-sum", Format: FormatQQWry, File: "sum.dat", Types: TypesIPv4},
+		{Name: "test-format", Format: FormatQQWry, File: "format.dat", Types: TypesIPv4},
+	})
+	mirror := filepath.Join(dir, "mirror")
+	if err := os.Mkdir(mirror, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := func(name, file string, data []byte) ManifestEntry {
+		sum := sha256.Sum256(data)
+		return ManifestEntry{Name: name, Format: FormatQQWry, File: file, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+	}
+	qqwry := testDatabases(t)[FormatQQWry]
+	tampered := append([]byte{}, qqwry...)
+	tampered[len(tampered)-1] = 1
+	garbage := []byte("not a database!")
+	manifest := Manifest{Version: manifestVersion, Databases: []ManifestEntry{
+		entry("test-good", "good.dat", qqwry),
+		// the file on the mirror differs from the manifest
+		entry("test-sum", "sum.dat", qqwry),
+		// the checksum matches but the file is no qqwry database
+		entry("test-format", "format.dat", garbage),
+	}}
+	manifestData, _ := json.Marshal(manifest)
+	for name, data := range map[string][]byte{ManifestName: manifestData, "good.dat": qqwry, "sum.dat": tampered, "format.dat": garbage} {
+		if err := os.WriteFile(filepath.Join(mirror, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mirrorUrl := "file://" + filepath.ToSlash(mirror)
+	fetched, err := fetchManifest(mirrorUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"test-good": "", "test-sum": "SHA-256 校验失败", "test-format": "不是有效的 qqwry 数据库", "test-none": "没有数据库"} {
+		update, _ := getMirrorUpdateFunc(mirrorUrl, fetched, name)
+		err := update(nil)
+		if want == "" {
+			if err != nil {
+				t.Errorf("mirror update of %s: %v", name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("mirror update of %s: err = %v, want %q", name, err, want)
+		}
+	}
+
+	if data, _ := os.ReadFile(dataFilePath("good.dat")); !bytes.Equal(data, qqwry) {
+		t.Error("good.dat was not installed")
+	}
+	for _, name := range []string{"sum.dat", "format.dat"} {
+		if _, err := os.Stat(dataFilePath(name)); err == nil {
+			t.Errorf("the rejected %s was installed", name)
+		}
+	}
+}
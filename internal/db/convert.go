@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/pkg/geoip"
+	"github.com/abc1763613206/nabili/pkg/iprange"
+	"github.com/abc1763613206/nabili/pkg/nbdb"
+	"github.com/abc1763613206/nabili/pkg/wry"
+)
+
+// Convert targets
+const (
+	ConvertMMDB  = "mmdb"
+	ConvertNBDB  = "nbdb"
+	ConvertCSV   = "csv"
+	ConvertJSONL = "jsonl"
+	ConvertQQwry = "qqwry"
+	ConvertZX    = "zxipv6wry"
+)
+
+type ConvertOptions struct {
+	// From is a database name or file path
+	From string
+	// To is the target format, detected from the output extension if empty
+	To string
+	// Lang is the language key of names in MMDB output
+	Lang string
+}
+
+// Convert walks every range of a database and writes it in another format,
+// output - writes csv and jsonl to stdout
+func Convert(output string, opts ConvertOptions) (err error) {
+	to := opts.To
+	if to == "" {
+		to = strings.TrimPrefix(filepath.Ext(output), ".")
+		if to == "json" {
+			to = ConvertJSONL
+		}
+	}
+	switch to {
+	case ConvertMMDB, ConvertNBDB, ConvertCSV, ConvertJSONL, ConvertQQwry, ConvertZX:
+	case "dat":
+		return fmt.Errorf("无法从扩展名判断目标格式，请使用 --to %s 或 --to %s", ConvertQQwry, ConvertZX)
+	default:
+		return fmt.Errorf("不支持的目标格式 %q，可选: mmdb, nbdb, csv, jsonl, qqwry, zxipv6wry", to)
+	}
+
+	adb, src, err := openIterable(opts.From)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	} else if to != ConvertCSV && to != ConvertJSONL {
+		return fmt.Errorf("%s 格式不能输出到标准输出", to)
+	}
+
+	count := 0
+	switch to {
+	case ConvertCSV, ConvertJSONL:
+		enc := iprange.NewCSVEncoder(w)
+		if to == ConvertJSONL {
+			enc = iprange.NewJSONLEncoder(w)
+		}
+		err = src.Ranges(func(r iprange.Range) error {
+			count++
+			return enc.Encode(r)
+		})
+		if err == nil {
+			err = enc.Flush()
+		}
+	default:
+		var ranges []iprange.Range
+		err = src.Ranges(func(r iprange.Range) error {
+			ranges = append(ranges, r)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		count = len(ranges)
+		switch to {
+		case ConvertNBDB:
+			err = nbdb.Write(w, ranges, []string{adb.Name})
+		case ConvertQQwry, ConvertZX:
+			var records []wry.Record
+			records, count = wryRecords(ranges, to == ConvertQQwry)
+			if to == ConvertQQwry {
+				err = wry.WriteQQwry(w, records)
+			} else {
+				err = wry.WriteZX(w, records, 8)
+			}
+		default:
+			err = geoip.Write(w, ranges, geoip.WriteOptions{
+				Description: fmt.Sprintf("converted from %s by nabili", filepath.Base(adb.Name)),
+				Lang:        convertLang(adb, opts.Lang),
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if output != "-" {
+		log.Printf("已将 %s 的 %d 个地址段转换为 %s 格式: %s\n", adb.Name, count, to, output)
+	}
+	return nil
+}
+
+// wryRecords picks the ranges of one family, qqwry holds IPv4 and ZX holds IPv6
+func wryRecords(ranges []iprange.Range, v4 bool) ([]wry.Record, int) {
+	var records []wry.Record
+	for _, r := range ranges {
+		if r.Start.Is4() != v4 {
+			continue
+		}
+		country, area := r.Location()
+		records = append(records, wry.Record{Start: r.Start, End: r.End, Country: country, Area: area})
+	}
+	return records, len(records)
+}
+
+// convertLang returns the language of names written to MMDB,
+// the language of the source database is used if not given
+func convertLang(adb *DB, lang string) string {
+	if lang != "" {
+		return lang
+	}
+	if len(adb.Languages) == 1 && adb.Languages[0] != LanguagesAll[0] {
+		return adb.Languages[0]
+	}
+	if langs := formatLanguages[adb.Format]; len(langs) == 1 && langs[0] != LanguagesAll[0] {
+		return langs[0]
+	}
+	if lang = viper.GetString("selected.lang"); lang != "" {
+		return lang
+	}
+	return "zh-CN"
+}
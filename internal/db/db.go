@@ -2,10 +2,11 @@ package db
 
 import (
 	"log"
-	"net"
+	"net/netip"
 
 	"github.com/spf13/viper"
 
+	"github.com/abc1763613206/nabili/pkg/bogon"
 	"github.com/abc1763613206/nabili/pkg/cdn"
 	"github.com/abc1763613206/nabili/pkg/dbif"
 	"github.com/abc1763613206/nabili/pkg/geoip"
@@ -34,7 +35,7 @@ func GetDB(typ dbif.QueryType) (db dbif.DB) {
 	case dbif.TypeIPv4:
 		// Command line flag takes highest priority
 		if CmdIPv4DB != "" {
-			db = getDbByNameStrict(CmdIPv4DB).get()
+			db = getCmdDB(CmdIPv4DB, typ).get()
 			break
 		}
 		
@@ -52,7 +53,7 @@ func GetDB(typ dbif.QueryType) (db dbif.DB) {
 	case dbif.TypeIPv6:
 		// Command line flag takes highest priority
 		if CmdIPv6DB != "" {
-			db = getDbByNameStrict(CmdIPv6DB).get()
+			db = getCmdDB(CmdIPv6DB, typ).get()
 			break
 		}
 		
@@ -87,30 +88,93 @@ func GetDB(typ dbif.QueryType) (db dbif.DB) {
 	return
 }
 
+// getCmdDB returns the database selected by --db4 or --db6, which has to support typ
+func getCmdDB(name string, typ dbif.QueryType) *DB {
+	d := getDbByNameStrict(name)
+	if !d.supports(typ) {
+		log.Fatalf("数据库 %s 不支持 %s 查询\n", name, queryDBTypes[typ])
+	}
+	return d
+}
+
 func Find(typ dbif.QueryType, query string) *Result {
 	if result, found := queryCache.Load(query); found {
 		return result.(*Result)
 	}
-	// Convert NAT64 64:ff9b::/96 to IPv4
-	if typ == dbif.TypeIPv6 {
-		ip := net.ParseIP(query)
-		if ip != nil {
-			_, NAT64, _ := net.ParseCIDR("64:ff9b::/96")
-			if NAT64.Contains(ip) {
-				ip4 := make(net.IP, 4)
-				copy(ip4, ip[12:16])
-				query = ip4.String()
-				typ = dbif.TypeIPv4
+
+	res, err := resolve(typ, query, true, true, func(typ dbif.QueryType, query string) (*Result, error) {
+		db := GetDB(typ)
+		result, err := db.Find(query)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Source: db.Name(), Result: result}, nil
+	})
+	if err != nil {
+		return nil
+	}
+	queryCache.Store(query, res)
+	return res
+}
+
+// resolve looks up an address in the order shared by Find and FindIn: the overlay
+// if overlay is set, the IPv4 address embedded in a transition address if embedded
+// is set, the special-purpose classes and at last lookup
+func resolve(typ dbif.QueryType, query string, overlay, embedded bool, lookup func(dbif.QueryType, string) (*Result, error)) (*Result, error) {
+	if typ != dbif.TypeIPv4 && typ != dbif.TypeIPv6 {
+		return lookup(typ, query)
+	}
+	ip, err := netip.ParseAddr(query)
+	if err != nil {
+		return lookup(typ, query)
+	}
+	class, special := bogon.Classify(ip)
+
+	// user maintained ranges take precedence over the public databases,
+	// also over the address embedded in a transition address they cover
+	if overlay {
+		if res := findOverlay(query); res != nil {
+			if special {
+				res.Class = class.Name
+			}
+			return res, nil
+		}
+	}
+
+	// Locate the IPv4 address embedded in NAT64, 6to4, Teredo and other transition addresses
+	if embedded && typ == dbif.TypeIPv6 {
+		if e, found := transitionDecoder().Decode(ip); found {
+			res, err := resolve(dbif.TypeIPv4, e.Inner.String(), overlay, embedded, lookup)
+			if err != nil {
+				return nil, err
+			}
+			inner := *res
+			inner.Transition = &e
+			if inner.Class == "" {
+				inner.Class = class.Name
 			}
+			return &inner, nil
 		}
 	}
 
-	db := GetDB(typ)
+	// special-purpose addresses are never sent to databases or remote APIs
+	if special {
+		return findBogon(typ, query), nil
+	}
+	return lookup(typ, query)
+}
+
+// findOverlay looks up the overlay database selected by selected.overlay,
+// it returns nil if no overlay is selected or the query is not covered
+func findOverlay(query string) *Result {
+	selected := viper.GetString("selected.overlay")
+	if selected == "" {
+		return nil
+	}
+	db := getDbByName(selected).get()
 	result, err := db.Find(query)
 	if err != nil {
 		return nil
 	}
-	res := &Result{db.Name(), result}
-	queryCache.Store(query, res)
-	return res
+	return &Result{Source: db.Name(), Result: result}
 }
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/pkg/dbif"
+)
+
+type stubResult string
+
+func (r stubResult) String() string {
+	return string(r)
+}
+
+// stubDB answers the queries in results, other queries fail the test if t is set
+type stubDB struct {
+	t       *testing.T
+	name    string
+	results map[string]string
+}
+
+func (d stubDB) Find(query string, params ...string) (fmt.Stringer, error) {
+	if text, found := d.results[query]; found {
+		return stubResult(text), nil
+	}
+	if d.t != nil {
+		d.t.Errorf("%s was sent to %s", query, d.name)
+	}
+	return nil, errors.New("not found")
+}
+
+func (d stubDB) Name() string {
+	return d.name
+}
+
+func TestFindOrder(t *testing.T) {
+	defer SetDB(dbif.TypeIPv4, stubDB{t, "ipv4", map[string]string{"8.8.8.8": "Google"}})()
+	defer SetDB(dbif.TypeIPv6, stubDB{t, "ipv6", map[string]string{"2001:4860::8888": "Google IPv6"}})()
+
+	NameDBMap["test-overlay"] = &DB{Name: "test-overlay", Format: FormatOverlay}
+	dbNameCache["test-overlay"] = stubDB{name: "overlay", results: map[string]string{
+		"10.1.1.1":      "office",
+		"2002:a00:1::1": "tunnel",
+	}}
+	viper.Set("selected.overlay", "test-overlay")
+	defer func() {
+		viper.Set("selected.overlay", "")
+		delete(NameDBMap, "test-overlay")
+		delete(dbNameCache, "test-overlay")
+	}()
+
+	tests := []struct {
+		typ        dbif.QueryType
+		query      string
+		source     string
+		text       string
+		class      string
+		transition bool
+	}{
+		{dbif.TypeIPv4, "8.8.8.8", "ipv4", "Google", "", false},
+		{dbif.TypeIPv4, "10.0.0.1", "bogon", "私有地址", "private", false},
+		// the overlay covers special-purpose addresses
+		{dbif.TypeIPv4, "10.1.1.1", "overlay", "office", "private", false},
+		{dbif.TypeIPv6, "2001:4860::8888", "ipv6", "Google IPv6", "", false},
+		// the embedded IPv4 address goes through the overlay, bogon and the IPv4 database
+		{dbif.TypeIPv6, "64:ff9b::808:808", "ipv4", "Google", "", true},
+		{dbif.TypeIPv6, "64:ff9b::a00:1", "bogon", "私有地址", "private", true},
+		{dbif.TypeIPv6, "64:ff9b::a01:101", "overlay", "office", "private", true},
+		// the overlay covers the transition address itself before it is decoded
+		{dbif.TypeIPv6, "2002:a00:1::1", "overlay", "tunnel", "6to4", false},
+		{dbif.TypeIPv6, "2002:a00:2::1", "bogon", "私有地址", "private", true},
+	}
+	for _, tt := range tests {
+		res := Find(tt.typ, tt.query)
+		if res == nil {
+			t.Errorf("Find(%s) = nil", tt.query)
+			continue
+		}
+		if res.Source != tt.source || res.Result.String() != tt.text || res.Class != tt.class || (res.Transition != nil) != tt.transition {
+			t.Errorf("Find(%s) = %s %q class %q transition %v, want %s %q class %q transition %v",
+				tt.query, res.Source, res.Result, res.Class, res.Transition != nil, tt.source, tt.text, tt.class, tt.transition)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package db
 
 import (
 	"github.com/abc1763613206/nabili/pkg/cdn"
+	"github.com/abc1763613206/nabili/pkg/geoip"
 	"github.com/abc1763613206/nabili/pkg/ip2region"
+	"github.com/abc1763613206/nabili/pkg/psl"
 	"github.com/abc1763613206/nabili/pkg/qqwry"
+	"github.com/abc1763613206/nabili/pkg/zxipv6wry"
 )
 
 func GetDefaultDBList() List {
@@ -25,10 +28,13 @@ func GetDefaultDBList() List {
 				"zxipv6",
 				"zx",
 			},
-			Format:    FormatZXIPv6Wry,
-			File:      "zxipv6wry.db",
-			Languages: LanguagesZH,
-			Types:     TypesIPv6,
+			Format:       FormatZXIPv6Wry,
+			File:         "zxipv6wry.db",
+			Languages:    LanguagesZH,
+			Types:        TypesIPv6,
+			DownloadUrls: zxipv6wry.DownloadUrls,
+			Archive:      string(zxipv6wry.Archive),
+			Member:       zxipv6wry.Member,
 		},
 		&DB{
 			Name: "geoip",
@@ -47,10 +53,24 @@ func GetDefaultDBList() List {
 			NameAlias: []string{
 				"db-ip",
 			},
-			Format:    FormatMMDB,
-			File:      "dbip.mmdb",
-			Languages: LanguagesAll,
-			Types:     TypesIP,
+			Format:       FormatMMDB,
+			File:         "dbip.mmdb",
+			Languages:    LanguagesAll,
+			Types:        TypesIP,
+			DownloadUrls: geoip.DBIPDownloadUrls,
+			Archive:      string(geoip.DBIPArchive),
+		},
+		&DB{
+			Name: "asn",
+			NameAlias: []string{
+				"dbip-asn",
+			},
+			Format:       FormatMMDB,
+			File:         "dbip-asn.mmdb",
+			Languages:    LanguagesAll,
+			Types:        TypesASN,
+			DownloadUrls: geoip.DBIPASNDownloadUrls,
+			Archive:      string(geoip.DBIPArchive),
 		},
 		&DB{
 			Name:      "ipip",
@@ -77,6 +97,13 @@ func GetDefaultDBList() List {
 			Languages: LanguagesEN,
 			Types:     TypesIP,
 		},
+		&DB{
+			Name:      "overlay",
+			Format:    FormatOverlay,
+			File:      "overlay.yml",
+			Languages: LanguagesAll,
+			Types:     TypesIP,
+		},
 
 		&DB{
 			Name:         "cdn",
@@ -86,6 +113,17 @@ func GetDefaultDBList() List {
 			Types:        TypesCDN,
 			DownloadUrls: cdn.DownloadUrls,
 		},
+		&DB{
+			Name: "psl",
+			NameAlias: []string{
+				"public-suffix",
+			},
+			Format:       FormatPSL,
+			File:         "public_suffix_list.dat",
+			Languages:    LanguagesAll,
+			Types:        TypesCDN,
+			DownloadUrls: psl.DownloadUrls,
+		},
 		&DB{
 			Name:      "bili",
 			Format:    FormatRemote,
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/abc1763613206/nabili/pkg/geoip"
+	"github.com/abc1763613206/nabili/pkg/ip2location"
+	"github.com/abc1763613206/nabili/pkg/ip2region"
+	"github.com/abc1763613206/nabili/pkg/ipip"
+	"github.com/abc1763613206/nabili/pkg/nbdb"
+	"github.com/abc1763613206/nabili/pkg/qqwry"
+	"github.com/abc1763613206/nabili/pkg/zxipv6wry"
+)
+
+var ErrUnknownFormat = errors.New("无法识别的数据库格式")
+
+// formatDetectors are tried in order, formats with a magic header come first
+// since the qqwry check only relies on the index offsets
+var formatDetectors = []struct {
+	format Format
+	check  func([]byte) bool
+}{
+	{FormatNBDB, nbdb.CheckFile},
+	{FormatZXIPv6Wry, zxipv6wry.CheckFile},
+	{FormatMMDB, geoip.CheckFile},
+	{FormatIPIP, ipip.CheckFile},
+	{FormatIP2Region, ip2region.CheckFile},
+	{FormatIP2Location, ip2location.CheckFile},
+	{FormatQQWry, qqwry.CheckFile},
+}
+
+// DetectFormat detects the database format from the file content
+func DetectFormat(data []byte) (Format, error) {
+	for _, detector := range formatDetectors {
+		if detector.check(data) {
+			return detector.format, nil
+		}
+	}
+	return "", ErrUnknownFormat
+}
+
+// CheckFormat reports whether data is a valid database of format
+func CheckFormat(format Format, data []byte) bool {
+	for _, detector := range formatDetectors {
+		if detector.format == format {
+			return detector.check(data)
+		}
+	}
+	// formats without a detector, like cdn-yml, are not checked
+	return true
+}
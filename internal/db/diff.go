@@ -0,0 +1,142 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+type DiffOptions struct {
+	JSON bool
+	// SummaryOnly prints the counts by location without the changed ranges
+	SummaryOnly bool
+}
+
+// DiffSummary counts the changed ranges of a country and province
+type DiffSummary struct {
+	Type     string `json:"type"`
+	Location string `json:"location"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Changed  int    `json:"changed"`
+}
+
+// Diff compares two versions of a database, each one is a database name or file path
+func Diff(w io.Writer, oldName, newName string, opts DiffOptions) error {
+	oldRanges, err := loadRanges(oldName)
+	if err != nil {
+		return err
+	}
+	newRanges, err := loadRanges(newName)
+	if err != nil {
+		return err
+	}
+
+	summaries := map[string]*DiffSummary{}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	err = iprange.Diff(oldRanges, newRanges, func(c iprange.Change) error {
+		// removed ranges count for the old location, others for the new one
+		fields := c.New
+		if fields == nil {
+			fields = c.Old
+		}
+		location := strings.TrimSpace(fields.Country + " " + fields.Region)
+		s, found := summaries[location]
+		if !found {
+			s = &DiffSummary{Type: "summary", Location: location}
+			summaries[location] = s
+		}
+		switch c.Kind {
+		case iprange.Added:
+			s.Added++
+		case iprange.Removed:
+			s.Removed++
+		case iprange.Changed:
+			s.Changed++
+		}
+
+		if opts.SummaryOnly {
+			return nil
+		}
+		if opts.JSON {
+			return enc.Encode(c)
+		}
+		_, err := fmt.Fprintln(w, formatChange(c))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	list := make([]*DiffSummary, 0, len(summaries))
+	total := DiffSummary{}
+	for _, s := range summaries {
+		list = append(list, s)
+		total.Added += s.Added
+		total.Removed += s.Removed
+		total.Changed += s.Changed
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if na, nb := a.Added+a.Removed+a.Changed, b.Added+b.Removed+b.Changed; na != nb {
+			return na > nb
+		}
+		return a.Location < b.Location
+	})
+
+	if opts.JSON {
+		for _, s := range list {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !opts.SummaryOnly {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "共 %d 个新增, %d 个删除, %d 个变更\n", total.Added, total.Removed, total.Changed)
+	if len(list) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "ADDED\tREMOVED\tCHANGED\t\tLOCATION")
+	for _, s := range list {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t\t%s\n", s.Added, s.Removed, s.Changed, s.Location)
+	}
+	return tw.Flush()
+}
+
+func formatChange(c iprange.Change) string {
+	span := fmt.Sprintf("%s - %s", c.Start, c.End)
+	switch c.Kind {
+	case iprange.Added:
+		return color.GreenString("+ %s", span) + "  " + c.New.String()
+	case iprange.Removed:
+		return color.RedString("- %s", span) + "  " + c.Old.String()
+	}
+	return color.YellowString("~ %s", span) + "  " + c.Old.String() + " → " + c.New.String()
+}
+
+// loadRanges reads all ranges of a database name or file path
+func loadRanges(nameOrPath string) ([]iprange.Range, error) {
+	_, src, err := openIterable(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+	var ranges []iprange.Range
+	err = src.Ranges(func(r iprange.Range) error {
+		ranges = append(ranges, r)
+		return nil
+	})
+	return ranges, err
+}
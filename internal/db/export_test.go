@@ -0,0 +1,25 @@
+package db
+
+import "github.com/abc1763613206/nabili/pkg/dbif"
+
+// SetDB makes Find use d for typ until restore is called
+func SetDB(typ dbif.QueryType, d dbif.DB) (restore func()) {
+	saved, found := dbTypeCache[typ]
+	dbTypeCache[typ] = d
+	clearQueryCache()
+	return func() {
+		if found {
+			dbTypeCache[typ] = saved
+		} else {
+			delete(dbTypeCache, typ)
+		}
+		clearQueryCache()
+	}
+}
+
+func clearQueryCache() {
+	queryCache.Range(func(key, _ any) bool {
+		queryCache.Delete(key)
+		return true
+	})
+}
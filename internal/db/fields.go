@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abc1763613206/nabili/pkg/bogon"
+	"github.com/abc1763613206/nabili/pkg/cdn"
+	"github.com/abc1763613206/nabili/pkg/geoip"
+	"github.com/abc1763613206/nabili/pkg/ip2location"
+	"github.com/abc1763613206/nabili/pkg/ip2region"
+	"github.com/abc1763613206/nabili/pkg/ipip"
+	"github.com/abc1763613206/nabili/pkg/iprange"
+	"github.com/abc1763613206/nabili/pkg/overlay"
+	"github.com/abc1763613206/nabili/pkg/remote"
+	"github.com/abc1763613206/nabili/pkg/wry"
+)
+
+// Fields returns the normalized location fields of the result, CDN results set the provider
+// as ISP, overlay labels named like fields are used as is and special-purpose addresses have none
+func (r Result) Fields() iprange.Fields {
+	return resultFields(r.Result)
+}
+
+func resultFields(res fmt.Stringer) iprange.Fields {
+	switch res := res.(type) {
+	case iprange.Fields:
+		return res
+	case wry.Result:
+		return wryFields(res)
+	case *wry.Result:
+		return wryFields(*res)
+	case geoip.Result:
+		return iprange.Fields{Country: res.Country, CountryCode: res.CountryCode, City: res.Area}
+	case ipip.Result:
+		return iprange.Fields{Country: res.Country, Region: res.Region, City: res.City}
+	case ip2location.Result:
+		return iprange.Fields{Country: res.Country, Region: res.Region, City: res.City}
+	case overlay.Result:
+		var f iprange.Fields
+		for k, v := range res.Labels {
+			f.Set(k, v)
+		}
+		return f
+	case cdn.CDNResult:
+		return iprange.Fields{ISP: res.Name}
+	case *remote.RemoteResult:
+		return resultFields(res.Result)
+	case *remote.BiliResult:
+		return iprange.Fields{Country: res.Country, Region: res.Province, City: res.City, ISP: res.ISP}
+	case bogon.Result, nil:
+		return iprange.Fields{}
+	}
+	// remote sources return a space separated location
+	return iprange.ParseLocation(strings.Join(strings.Fields(res.String()), "–"), "")
+}
+
+// wryFields also handles ip2region results, their unknown fields are dropped from the region
+func wryFields(res wry.Result) iprange.Fields {
+	if strings.Count(res.Country, "|") >= 3 && res.Area == "" {
+		return ip2region.ParseRegion(res.Country)
+	}
+	return iprange.ParseLocation(res.Country, res.Area)
+}
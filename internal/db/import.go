@@ -0,0 +1,182 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+type ImportOptions struct {
+	// As is the name of the database to import into. If empty the configured
+	// database with the same file name is used, or else the only one with the
+	// detected format
+	As string
+	// Register adds a new entry to the config if no database is named As
+	Register bool
+}
+
+// Import imports a database file, or every database file in a directory,
+// into the data dir under the configured file name
+func Import(path string, opts ImportOptions) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		_, err = ImportFile(path, opts)
+		return err
+	}
+
+	if opts.As != "" {
+		return fmt.Errorf("--as 不能与目录一起使用")
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	imported := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if _, err := ImportFile(filepath.Join(path, entry.Name()), opts); err != nil {
+			log.Printf("跳过 %s: %v\n", entry.Name(), err)
+			continue
+		}
+		imported++
+	}
+	if imported == 0 {
+		return fmt.Errorf("%s 中没有可导入的数据库", path)
+	}
+	return nil
+}
+
+// ImportFile detects and validates a single database file and copies it into the data dir
+func ImportFile(path string, opts ImportOptions) (*DB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := importTarget(format, path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := dataFilePath(target.File)
+	if err := common.SaveFile(dst, data); err != nil {
+		return nil, err
+	}
+	log.Printf("已将 %s 格式的 %s 导入为 %s 数据库: %s\n", format, path, target.Name, dst)
+	return target, nil
+}
+
+func importTarget(format Format, path string, opts ImportOptions) (*DB, error) {
+	fileName := filepath.Base(path)
+	if opts.As == "" {
+		var matched []string
+		var target *DB
+		for _, adb := range configuredDBList() {
+			if strings.EqualFold(filepath.Base(adb.File), fileName) {
+				if adb.Format != format {
+					return nil, fmt.Errorf("数据库 %s 的格式为 %s，但导入的文件为 %s 格式", adb.Name, adb.Format, format)
+				}
+				return adb, nil
+			}
+			if adb.Format == format {
+				matched = append(matched, adb.Name)
+				target = adb
+			}
+		}
+		switch len(matched) {
+		case 0:
+			return nil, fmt.Errorf("配置中没有 %s 格式的数据库，请使用 --as 指定名称", format)
+		case 1:
+			return target, nil
+		default:
+			return nil, fmt.Errorf("配置中有多个 %s 格式的数据库 (%s)，请使用 --as 指定名称", format, strings.Join(matched, ", "))
+		}
+	}
+
+	if adb, found := NameDBMap[opts.As]; found {
+		if adb.Format != format {
+			return nil, fmt.Errorf("数据库 %s 的格式为 %s，但导入的文件为 %s 格式", adb.Name, adb.Format, format)
+		}
+		return adb, nil
+	}
+
+	if !opts.Register {
+		return nil, fmt.Errorf("数据库 %s 不存在，可使用 --register 添加到配置中", opts.As)
+	}
+	// another database would be overwritten by this one on the next import or update
+	for _, adb := range configuredDBList() {
+		if strings.EqualFold(filepath.Base(adb.File), fileName) {
+			return nil, fmt.Errorf("文件名 %s 已被数据库 %s 使用，请先重命名文件", fileName, adb.Name)
+		}
+	}
+	adb := &DB{
+		Name:      opts.As,
+		Format:    format,
+		File:      fileName,
+		Languages: formatLanguages[format],
+		Types:     formatTypes[format],
+	}
+	if err := registerDB(adb); err != nil {
+		return nil, err
+	}
+	return adb, nil
+}
+
+var formatTypes = map[Format][]Type{
+	FormatQQWry:       TypesIPv4,
+	FormatZXIPv6Wry:   TypesIPv6,
+	FormatMMDB:        TypesIP,
+	FormatIPIP:        TypesIP,
+	FormatIP2Region:   TypesIPv4,
+	FormatIP2Location: TypesIP,
+	FormatNBDB:        TypesIP,
+}
+
+var formatLanguages = map[Format][]string{
+	FormatQQWry:       LanguagesZH,
+	FormatZXIPv6Wry:   LanguagesZH,
+	FormatMMDB:        LanguagesAll,
+	FormatIPIP:        LanguagesZH,
+	FormatIP2Region:   LanguagesZH,
+	FormatIP2Location: LanguagesEN,
+	FormatNBDB:        LanguagesAll,
+}
+
+// configuredDBList returns the databases in config order
+func configuredDBList() List {
+	dbList := List{}
+	if err := viper.UnmarshalKey("databases", &dbList); err != nil {
+		log.Fatalln("Config invalid:", err)
+	}
+	return dbList
+}
+
+// registerDB appends a database to the config file
+func registerDB(adb *DB) error {
+	dbList := append(configuredDBList(), adb)
+	viper.Set("databases", dbList)
+	if err := viper.WriteConfig(); err != nil {
+		return err
+	}
+
+	NameDBMap.From(List{adb})
+	TypeDBMap.From(List{adb})
+	log.Printf("已将数据库 %s 添加到配置中\n", adb.Name)
+	return nil
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lionsoul2014/ip2region/binding/golang/xdb"
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/pkg/geoip"
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// testDatabases returns minimal files with valid headers for the formats
+// that can be detected
+func testDatabases(t *testing.T) map[Format][]byte {
+	t.Helper()
+	// an empty index right after the header
+	qqwry := make([]byte, 15)
+	binary.LittleEndian.PutUint32(qqwry[0:], 8)
+	binary.LittleEndian.PutUint32(qqwry[4:], 8)
+
+	// one record of a 3 byte offset and an 8 byte address
+	zx := make([]byte, 24+11)
+	copy(zx, "IPDB")
+	zx[6], zx[7] = 3, 8
+	binary.LittleEndian.PutUint64(zx[8:], 1)
+	binary.LittleEndian.PutUint64(zx[16:], 24)
+
+	var mmdb bytes.Buffer
+	ranges := []iprange.Range{{Start: netip.MustParseAddr("1.0.0.0"), End: netip.MustParseAddr("1.0.0.255"), Fields: iprange.Fields{Country: "中国"}}}
+	if err := geoip.Write(&mmdb, ranges, geoip.WriteOptions{Lang: "zh-CN"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// one segment index block after the vector index
+	start := xdb.HeaderInfoLength + xdb.VectorIndexRows*xdb.VectorIndexCols*xdb.VectorIndexSize
+	xdbData := make([]byte, start+xdb.SegmentIndexBlockSize)
+	binary.LittleEndian.PutUint16(xdbData[0:], 2)
+	binary.LittleEndian.PutUint16(xdbData[2:], uint16(xdb.VectorIndexPolicy))
+	binary.LittleEndian.PutUint32(xdbData[8:], uint32(start))
+	binary.LittleEndian.PutUint32(xdbData[12:], uint32(start))
+
+	// a DB3 header with a single IPv4 row
+	bin := make([]byte, 128)
+	bin[0], bin[1], bin[2], bin[3], bin[4] = 3, 4, 24, 1, 1
+	binary.LittleEndian.PutUint32(bin[5:], 1)
+	binary.LittleEndian.PutUint32(bin[9:], 65)
+	bin[29] = 1
+
+	return map[Format][]byte{
+		FormatQQWry:       qqwry,
+		FormatZXIPv6Wry:   zx,
+		FormatMMDB:        mmdb.Bytes(),
+		FormatIP2Region:   xdbData,
+		FormatIP2Location: bin,
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	for format, data := range testDatabases(t) {
+		if got, err := DetectFormat(data); err != nil || got != format {
+			t.Errorf("DetectFormat(%s) = %s, %v", format, got, err)
+		}
+		if !CheckFormat(format, data) {
+			t.Errorf("CheckFormat(%s) rejected its own file", format)
+		}
+	}
+	for _, data := range [][]byte{nil, []byte("not a database"), []byte("IPDB\x00\x00\x09\x08")} {
+		if got, err := DetectFormat(data); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("DetectFormat(%q) = %s, %v", data, got, err)
+		}
+	}
+}
+
+// setupImport points the data dir and the config to a temp dir with the databases
+func setupImport(t *testing.T, dbs List) string {
+	t.Helper()
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	if err := os.Mkdir(dataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	savedDataDir, savedDatabases := constant.DataDirPath, viper.Get("databases")
+	constant.DataDirPath = dataDir
+	viper.SetConfigFile(filepath.Join(dir, "config.yaml"))
+	viper.Set("databases", dbs)
+	t.Cleanup(func() {
+		constant.DataDirPath = savedDataDir
+		viper.Set("databases", savedDatabases)
+	})
+	for _, adb := range dbs {
+		NameDBMap[adb.Name] = adb
+		name := adb.Name
+		t.Cleanup(func() { delete(NameDBMap, name) })
+	}
+	return dir
+}
+
+func TestImportFile(t *testing.T) {
+	dir := setupImport(t, List{
+		{Name: "test-qqwry", Format: FormatQQWry, File: "qqwry.dat", Types: TypesIPv4},
+		{Name: "test-city", Format: FormatMMDB, File: "GeoLite2-City.mmdb", Types: TypesIP},
+		{Name: "test-asn", Format: FormatMMDB, File: "GeoLite2-ASN.mmdb", Types: TypesASN},
+	})
+	files := testDatabases(t)
+	write := func(name string, format Format) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, files[format], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		file   string
+		format Format
+		opts   ImportOptions
+		target string
+		err    string
+	}{
+		// the only database of the format
+		{"new.dat", FormatQQWry, ImportOptions{}, "test-qqwry", ""},
+		// the file name picks one of several databases of a format
+		{"GeoLite2-ASN.mmdb", FormatMMDB, ImportOptions{}, "test-asn", ""},
+		{"geolite2-city.mmdb", FormatMMDB, ImportOptions{}, "test-city", ""},
+		{"other.mmdb", FormatMMDB, ImportOptions{}, "", "多个 mmdb 格式的数据库"},
+		{"other.mmdb", FormatMMDB, ImportOptions{As: "test-city"}, "test-city", ""},
+		{"qqwry.dat", FormatMMDB, ImportOptions{}, "", "格式为 qqwry"},
+		{"zx.db", FormatZXIPv6Wry, ImportOptions{}, "", "没有 zxipv6wry 格式的数据库"},
+		{"zx.db", FormatZXIPv6Wry, ImportOptions{As: "test-qqwry"}, "", "格式为 qqwry"},
+		{"zx.db", FormatZXIPv6Wry, ImportOptions{As: "test-zx"}, "", "--register"},
+		// a registered file name must not clash with a configured database
+		{"GeoLite2-City.mmdb", FormatMMDB, ImportOptions{As: "test-new", Register: true}, "", "已被数据库 test-city 使用"},
+		{"zx.db", FormatZXIPv6Wry, ImportOptions{As: "test-zx", Register: true}, "test-zx", ""},
+	}
+	for _, tt := range tests {
+		target, err := ImportFile(write(tt.file, tt.format), tt.opts)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ImportFile(%s, %+v): err = %v, want %q", tt.file, tt.opts, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ImportFile(%s, %+v): %v", tt.file, tt.opts, err)
+			continue
+		}
+		if target.Name != tt.target {
+			t.Errorf("ImportFile(%s, %+v) imported into %s, want %s", tt.file, tt.opts, target.Name, tt.target)
+		}
+		if data, _ := os.ReadFile(dataFilePath(target.File)); !bytes.Equal(data, files[tt.format]) {
+			t.Errorf("ImportFile(%s, %+v) did not write %s", tt.file, tt.opts, target.File)
+		}
+	}
+	delete(NameDBMap, "test-zx")
+
+	registered := false
+	for _, adb := range configuredDBList() {
+		registered = registered || adb.Name == "test-zx" && adb.File == "zx.db" && adb.Format == FormatZXIPv6Wry
+	}
+	if !registered {
+		t.Error("test-zx was not added to the config")
+	}
+}
+
+func TestImportDir(t *testing.T) {
+	dir := setupImport(t, List{
+		{Name: "test-qqwry", Format: FormatQQWry, File: "qqwry.dat", Types: TypesIPv4},
+		{Name: "test-city", Format: FormatMMDB, File: "GeoLite2-City.mmdb", Types: TypesIP},
+		{Name: "test-asn", Format: FormatMMDB, File: "GeoLite2-ASN.mmdb", Types: TypesASN},
+	})
+	files := testDatabases(t)
+	src := filepath.Join(dir, "src")
+	if err := os.Mkdir(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"GeoLite2-City.mmdb": files[FormatMMDB],
+		"GeoLite2-ASN.mmdb":  files[FormatMMDB],
+		"other.mmdb":         files[FormatMMDB],
+		"mirror-qqwry.dat":   files[FormatQQWry],
+		"README.txt":         []byte("not a database"),
+	} {
+		if err := os.WriteFile(filepath.Join(src, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Import(src, ImportOptions{As: "test-city"}); err == nil {
+		t.Error("--as accepted with a directory")
+	}
+	if err := Import(src, ImportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the ambiguous other.mmdb is skipped instead of overwriting a database
+	for _, name := range []string{"GeoLite2-City.mmdb", "GeoLite2-ASN.mmdb", "qqwry.dat"} {
+		if _, err := os.Stat(filepath.Join(constant.DataDirPath, name)); err != nil {
+			t.Errorf("%s was not imported: %v", name, err)
+		}
+	}
+	entries, _ := os.ReadDir(constant.DataDirPath)
+	if len(entries) != 3 {
+		t.Errorf("data dir has %d files, want 3", len(entries))
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.Mkdir(empty, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Import(empty, ImportOptions{}); err == nil {
+		t.Error("importing an empty directory succeeded")
+	}
+}
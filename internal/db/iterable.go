@@ -0,0 +1,39 @@
+package db
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// iterableFormats are the formats whose ranges can be walked
+var iterableFormats = map[Format]bool{
+	FormatQQWry:     true,
+	FormatZXIPv6Wry: true,
+	FormatMMDB:      true,
+	FormatIP2Region: true,
+	FormatNBDB:      true,
+}
+
+// openIterable opens a configured database by name, or a database file by path
+func openIterable(nameOrPath string) (*DB, iprange.Iterable, error) {
+	adb, found := NameDBMap[nameOrPath]
+	if !found {
+		path := constant.WorkPath(nameOrPath)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("数据库 %s 不存在", nameOrPath)
+		}
+		format, err := DetectFormat(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", nameOrPath, err)
+		}
+		adb = &DB{Name: path, Format: format, File: path}
+	}
+	if !iterableFormats[adb.Format] {
+		return nil, nil, fmt.Errorf("数据库 %s 的格式 %s 不支持遍历", adb.Name, adb.Format)
+	}
+	return adb, adb.get().(iprange.Iterable), nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+// fetchManifest downloads the manifest of a mirror, which has the layout of an extracted bundle
+func fetchManifest(mirror string) (*Manifest, error) {
+	data, err := common.GetHttpClient().Get(mirrorURL(mirror, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("manifest 解析失败: %v", err)
+	}
+	return manifest, nil
+}
+
+func mirrorURL(mirror, file string) string {
+	return strings.TrimSuffix(mirror, "/") + "/" + url.PathEscape(file)
+}
+
+func getMirrorUpdateFunc(mirror string, manifest *Manifest, name string) (updateFunc, string) {
+	name = strings.TrimSpace(name)
+	if adb, found := NameDBMap[name]; found {
+		name = adb.Name
+	}
+
+	entry := manifest.find(name)
+	if entry == nil {
+		return func(progress common.ProgressFunc) error {
+			return fmt.Errorf("mirror 中没有数据库 %s", name)
+		}, name
+	}
+
+	if err := entry.checkFile(); err != nil {
+		return func(progress common.ProgressFunc) error {
+			return err
+		}, name
+	}
+
+	return func(progress common.ProgressFunc) error {
+		log.Printf("正在从 mirror 下载 %s 数据库...\n", entry.Name)
+		data, err := common.GetHttpClient().GetWithProgress(progress, mirrorURL(mirror, entry.File))
+		if err != nil {
+			return err
+		}
+		if err := entry.Verify(data); err != nil {
+			return err
+		}
+		return installBundleEntry(entry, data)
+	}, name
+}
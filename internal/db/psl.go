@@ -0,0 +1,33 @@
+package db
+
+import (
+	"log"
+	"os"
+	"sync"
+
+	"github.com/abc1763613206/nabili/pkg/psl"
+)
+
+var (
+	suffixList     *psl.List
+	suffixListOnce sync.Once
+)
+
+// PublicSuffixList returns the list downloaded by "nabili update --db psl",
+// the embedded snapshot is used if there is none or it is broken
+func PublicSuffixList() *psl.List {
+	suffixListOnce.Do(func() {
+		suffixList = psl.Default()
+		file := getDbByName("psl").File
+		if _, err := os.Stat(file); err != nil {
+			return
+		}
+		l, err := psl.NewList(file)
+		if err != nil {
+			log.Printf("公共后缀列表 %s 读取失败，使用内置列表: %v\n", file, err)
+			return
+		}
+		suffixList = l
+	})
+	return suffixList
+}
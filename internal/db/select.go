@@ -0,0 +1,159 @@
+package db
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/pkg/bogon"
+	"github.com/abc1763613206/nabili/pkg/dbif"
+)
+
+var queryDBTypes = map[dbif.QueryType]Type{
+	dbif.TypeIPv4:   TypeIPv4,
+	dbif.TypeIPv6:   TypeIPv6,
+	dbif.TypeDomain: TypeCDN,
+}
+
+// Names returns the sorted names and aliases of the databases supporting typ
+func Names(typ dbif.QueryType) []string {
+	names := NameMap{}
+	names.From(GetDefaultDBList())
+	for name, d := range NameDBMap {
+		names[name] = d
+	}
+	var list []string
+	for name, d := range names {
+		if d.supports(typ) {
+			list = append(list, name)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (d *DB) supports(typ dbif.QueryType) bool {
+	for _, t := range d.Types {
+		if t == queryDBTypes[typ] {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupDB is getDbByName without exiting on unknown names
+func lookupDB(name string, typ dbif.QueryType) (*DB, error) {
+	d := findDbByName(name, true)
+	if d == nil {
+		return nil, fmt.Errorf("数据库 %s 不存在", name)
+	}
+	if !d.supports(typ) {
+		return nil, fmt.Errorf("数据库 %s 不支持 %s 查询", name, queryDBTypes[typ])
+	}
+	return d, nil
+}
+
+// Select switches the database used by Find for typ like --db4 and --db6. The database
+// is opened first without downloading a missing file, nothing changes if that fails
+func Select(typ dbif.QueryType, name string) error {
+	d, err := lookupDB(name, typ)
+	if err != nil {
+		return err
+	}
+	if _, err := d.openLocal(); err != nil {
+		return fmt.Errorf("数据库 %s 打开失败: %v", name, err)
+	}
+
+	switch typ {
+	case dbif.TypeIPv4:
+		CmdIPv4DB = name
+	case dbif.TypeIPv6:
+		CmdIPv6DB = name
+	default:
+		return fmt.Errorf("不能切换 %s 数据库", queryDBTypes[typ])
+	}
+	delete(dbTypeCache, typ)
+	queryCache.Range(func(key, _ any) bool {
+		queryCache.Delete(key)
+		return true
+	})
+	return nil
+}
+
+// openLocal is open without downloading a missing file, which would block the caller
+func (d *DB) openLocal() (dbif.DB, error) {
+	if _, found := dbNameCache[d.Name]; !found && d.Format != FormatRemote && d.File != "" {
+		if _, err := os.Stat(d.File); os.IsNotExist(err) {
+			return nil, fmt.Errorf("文件 %s 不存在，请使用 nabili update --db %s 下载", d.File, d.Name)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return d.open()
+}
+
+// FindIn looks up query in the database name instead of the selected one. Like Find
+// special-purpose addresses are not sent to it and the IPv4 address embedded in a
+// transition address is looked up instead if the database supports IPv4, the
+// overlay is skipped to compare the database itself
+func FindIn(name string, typ dbif.QueryType, query string) (*Result, error) {
+	d, err := lookupDB(name, typ)
+	if err != nil {
+		return nil, err
+	}
+	return resolve(typ, query, false, d.supports(dbif.TypeIPv4), func(typ dbif.QueryType, query string) (*Result, error) {
+		adb, err := d.openLocal()
+		if err != nil {
+			return nil, fmt.Errorf("数据库 %s 打开失败: %v", name, err)
+		}
+		result, err := adb.Find(query)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Source: adb.Name(), Result: result}, nil
+	})
+}
+
+// findBogon returns the result of a special-purpose address, nil for other queries
+func findBogon(typ dbif.QueryType, query string) *Result {
+	if typ != dbif.TypeIPv4 && typ != dbif.TypeIPv6 {
+		return nil
+	}
+	ip, err := netip.ParseAddr(query)
+	if err != nil {
+		return nil
+	}
+	class, special := bogon.Classify(ip)
+	if !special {
+		return nil
+	}
+	return &Result{
+		Source: "bogon",
+		Result: bogon.Result{Class: class, Text: class.Text(viper.GetString("selected.lang"))},
+		Class:  class.Name,
+	}
+}
+
+// Selected returns the name of the database GetDB opens for typ without opening it
+func Selected(typ dbif.QueryType) string {
+	cmd, key, zh := CmdIPv4DB, "selected.ipv4", "qqwry"
+	switch typ {
+	case dbif.TypeIPv6:
+		cmd, key, zh = CmdIPv6DB, "selected.ipv6", "zxipv6wry"
+	case dbif.TypeDomain:
+		cmd, key, zh = "", "selected.cdn", "cdn"
+	}
+	if cmd != "" {
+		return cmd
+	}
+	if selected := viper.GetString(key); selected != "" {
+		return selected
+	}
+	if lang := viper.GetString("selected.lang"); typ != dbif.TypeDomain && lang != "" && lang != "zh-CN" {
+		return "geoip"
+	}
+	return zh
+}
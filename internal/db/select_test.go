@@ -0,0 +1,101 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abc1763613206/nabili/pkg/dbif"
+)
+
+func TestASNNotSelectable(t *testing.T) {
+	for _, typ := range []dbif.QueryType{dbif.TypeIPv4, dbif.TypeIPv6} {
+		for _, name := range Names(typ) {
+			if name == "asn" || name == "dbip-asn" {
+				t.Errorf("Names(%d) lists %s", typ, name)
+			}
+		}
+		if err := Select(typ, "asn"); err == nil {
+			t.Errorf("Select(%d, asn) succeeded", typ)
+		}
+		if _, err := FindIn("asn", typ, "1.1.1.1"); err == nil {
+			t.Errorf("FindIn(asn, %d) succeeded", typ)
+		}
+	}
+}
+
+func TestSelectBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.dat")
+	if err := os.WriteFile(garbage, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []*DB{
+		{Name: "test-qqwry", Format: FormatQQWry, File: garbage, Types: TypesIPv4},
+		{Name: "test-zx", Format: FormatZXIPv6Wry, File: garbage, Types: TypesIPv6},
+		{Name: "test-mmdb", Format: FormatMMDB, File: garbage, Types: TypesIP},
+		{Name: "test-missing", Format: FormatQQWry, File: filepath.Join(dir, "missing.dat"), Types: TypesIP},
+	} {
+		NameDBMap[d.Name] = d
+		defer delete(NameDBMap, d.Name)
+
+		typ := dbif.QueryType(dbif.TypeIPv4)
+		if d.Types[0] == TypeIPv6 {
+			typ = dbif.TypeIPv6
+		}
+		// the process would have exited or started a download before
+		if err := Select(typ, d.Name); err == nil {
+			t.Errorf("Select(%s) succeeded", d.Name)
+		}
+		if _, err := FindIn(d.Name, typ, "1.1.1.1"); err == nil {
+			t.Errorf("FindIn(%s) succeeded", d.Name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.dat")); err == nil {
+		t.Error("the missing database was downloaded")
+	}
+	if err := Select(dbif.TypeIPv4, "test-missing"); err == nil || !strings.Contains(err.Error(), "不存在") {
+		t.Errorf("Select of a missing file: %v", err)
+	}
+}
+
+func TestFindInTransition(t *testing.T) {
+	for _, d := range []*DB{
+		{Name: "test-ip", Format: FormatMMDB, Types: TypesIP},
+		{Name: "test-ipv6", Format: FormatZXIPv6Wry, Types: TypesIPv6},
+	} {
+		NameDBMap[d.Name] = d
+		defer delete(NameDBMap, d.Name)
+	}
+	dbNameCache["test-ip"] = stubDB{t, "test-ip", map[string]string{"8.8.8.8": "Google"}}
+	dbNameCache["test-ipv6"] = stubDB{t, "test-ipv6", map[string]string{"64:ff9b::808:808": "NAT64"}}
+	defer delete(dbNameCache, "test-ip")
+	defer delete(dbNameCache, "test-ipv6")
+
+	tests := []struct {
+		name, query string
+		source      string
+		class       string
+		transition  bool
+	}{
+		{"test-ip", "64:ff9b::808:808", "test-ip", "", true},
+		// the embedded address is classified before the database is asked
+		{"test-ip", "64:ff9b::a00:1", "bogon", "private", true},
+		{"test-ip", "2002:a00:1::1", "bogon", "private", true},
+		// an IPv6 only database looks up the transition address itself
+		{"test-ipv6", "64:ff9b::808:808", "test-ipv6", "", false},
+		{"test-ipv6", "2002:a00:1::1", "bogon", "6to4", false},
+	}
+	for _, tt := range tests {
+		res, err := FindIn(tt.name, dbif.TypeIPv6, tt.query)
+		if err != nil {
+			t.Errorf("FindIn(%s, %s): %v", tt.name, tt.query, err)
+			continue
+		}
+		if res.Source != tt.source || res.Class != tt.class || (res.Transition != nil) != tt.transition {
+			t.Errorf("FindIn(%s, %s) = %s class %q transition %v, want %s class %q transition %v",
+				tt.name, tt.query, res.Source, res.Class, res.Transition != nil, tt.source, tt.class, tt.transition)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"log"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/pkg/transition"
+)
+
+var (
+	decoder     *transition.Decoder
+	decoderOnce sync.Once
+)
+
+// transitionDecoder returns the decoder with the local NAT64 prefixes in nat64.prefixes,
+// the prefixes may also be given as a comma separated list in NALI_NAT64_PREFIXES
+func transitionDecoder() *transition.Decoder {
+	decoderOnce.Do(func() {
+		var prefixes []netip.Prefix
+		for _, item := range viper.GetStringSlice("nat64.prefixes") {
+			for _, s := range strings.Split(item, ",") {
+				if s = strings.TrimSpace(s); s == "" {
+					continue
+				}
+				p, err := netip.ParsePrefix(s)
+				if err != nil {
+					log.Fatalln("Config invalid: nat64.prefixes:", err)
+				}
+				prefixes = append(prefixes, p)
+			}
+		}
+		var err error
+		if decoder, err = transition.NewDecoder(prefixes...); err != nil {
+			log.Fatalln("Config invalid: nat64.prefixes:", err)
+		}
+	})
+	return decoder
+}
@@ -10,8 +10,12 @@ import (
 	"github.com/abc1763613206/nabili/pkg/ip2location"
 	"github.com/abc1763613206/nabili/pkg/ip2region"
 	"github.com/abc1763613206/nabili/pkg/ipip"
+	"github.com/abc1763613206/nabili/pkg/nbdb"
+	"github.com/abc1763613206/nabili/pkg/overlay"
+	"github.com/abc1763613206/nabili/pkg/psl"
 	"github.com/abc1763613206/nabili/pkg/qqwry"
 	"github.com/abc1763613206/nabili/pkg/remote"
+	"github.com/abc1763613206/nabili/pkg/transition"
 	"github.com/abc1763613206/nabili/pkg/zxipv6wry"
 )
 
@@ -25,16 +29,28 @@ type DB struct {
 	Types     []Type
 
 	DownloadUrls []string `yaml:"download-urls,omitempty" mapstructure:"download-urls"`
+	// Archive is the compression of the downloaded file: zip, gz, tar.gz, 7z or xz,
+	// Member is the path of the database inside the archive
+	Archive string `yaml:"archive,omitempty" mapstructure:"archive"`
+	Member  string `yaml:"member,omitempty" mapstructure:"member"`
 }
 
 func (d *DB) get() (db dbif.DB) {
+	db, err := d.open()
+	if err != nil {
+		log.Fatalln("Database init failed:", err)
+	}
+	return db
+}
+
+// open opens the database, unlike get errors are returned
+func (d *DB) open() (db dbif.DB, err error) {
 	if db, found := dbNameCache[d.Name]; found {
-		return db
+		return db, nil
 	}
 
 	filePath := d.File
 
-	var err error
 	switch d.Format {
 	case FormatQQWry:
 		db, err = qqwry.NewQQwry(filePath)
@@ -48,8 +64,14 @@ func (d *DB) get() (db dbif.DB) {
 		db, err = ip2region.NewIp2Region(filePath)
 	case FormatIP2Location:
 		db, err = ip2location.NewIP2Location(filePath)
+	case FormatNBDB:
+		db, err = nbdb.NewNBDB(filePath)
+	case FormatOverlay:
+		db, err = overlay.NewOverlay(filePath)
 	case FormatCDNYml:
 		db, err = cdn.NewCDN(filePath)
+	case FormatPSL:
+		db, err = psl.NewList(filePath)
 	case FormatRemote:
 		switch d.Name {
 		case "bili":
@@ -68,11 +90,11 @@ func (d *DB) get() (db dbif.DB) {
 	}
 
 	if err != nil {
-		log.Fatalln("Database init failed:", err)
+		return nil, err
 	}
 
 	dbNameCache[d.Name] = db
-	return
+	return db, nil
 }
 
 type Format string
@@ -84,9 +106,12 @@ const (
 	FormatIPIP               = "ipip"
 	FormatIP2Region          = "ip2region"
 	FormatIP2Location        = "ip2location"
+	FormatNBDB               = "nbdb"
+	FormatOverlay            = "overlay"
 	FormatRemote             = "remote"
 
 	FormatCDNYml = "cdn-yml"
+	FormatPSL    = "psl"
 )
 
 var (
@@ -101,14 +126,17 @@ const (
 	TypeIPv4 Type = "IPv4"
 	TypeIPv6      = "IPv6"
 	TypeCDN       = "CDN"
+	// TypeASN databases are only read by FindASN, they cannot be selected for queries
+	TypeASN = "ASN"
 )
 
 var (
-	TypesAll  = []Type{TypeIPv4, TypeIPv6, TypeCDN}
+	TypesAll  = []Type{TypeIPv4, TypeIPv6, TypeCDN, TypeASN}
 	TypesIP   = []Type{TypeIPv4, TypeIPv6}
 	TypesIPv4 = []Type{TypeIPv4}
 	TypesIPv6 = []Type{TypeIPv6}
 	TypesCDN  = []Type{TypeCDN}
+	TypesASN  = []Type{TypeASN}
 )
 
 type List []*DB
@@ -150,6 +178,14 @@ func getDbByNameStrict(name string) (db *DB) {
 }
 
 func getDbByNameWithFallback(name string, allowFallback bool) (db *DB) {
+	if db = findDbByName(name, allowFallback); db == nil {
+		log.Fatalf("DB with name %s not found!\n", name)
+	}
+	return
+}
+
+// findDbByName is getDbByName returning nil instead of exiting if the name is unknown
+func findDbByName(name string, allowFallback bool) *DB {
 	if dbInfo, found := NameDBMap[name]; found {
 		return dbInfo
 	}
@@ -161,12 +197,21 @@ func getDbByNameWithFallback(name string, allowFallback bool) (db *DB) {
 			return dbInfo
 		}
 	}
-
-	log.Fatalf("DB with name %s not found!\n", name)
-	return
+	return nil
 }
 
 type Result struct {
 	Source string
 	common.Result
+	// Class is the special-purpose class of the address, see bogon.Registry
+	Class string
+	// Transition is set if the result is of the IPv4 address embedded in an IPv6 query
+	Transition *transition.Embedded
+}
+
+func (r Result) String() string {
+	if r.Transition == nil {
+		return r.Result.String()
+	}
+	return r.Transition.String() + " " + r.Result.String()
 }
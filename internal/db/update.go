@@ -2,32 +2,135 @@ package db
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
-	"time"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/viper"
 
 	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/pkg/archive"
+	"github.com/abc1763613206/nabili/pkg/common"
 	"github.com/abc1763613206/nabili/pkg/download"
+	"github.com/abc1763613206/nabili/pkg/geoip"
+	"github.com/abc1763613206/nabili/pkg/ip2location"
+	"github.com/abc1763613206/nabili/pkg/progress"
+	"github.com/abc1763613206/nabili/pkg/psl"
 	"github.com/abc1763613206/nabili/pkg/qqwry"
 	"github.com/abc1763613206/nabili/pkg/zxipv6wry"
 )
 
-func UpdateDB(dbNames ...string) {
+// DefaultUpdateParallel is the number of databases downloaded at the same time by default
+const DefaultUpdateParallel = 4
+
+// errUpdateSkipped is returned by update funcs of databases without auto update support
+var errUpdateSkipped = errors.New("暂不支持该类型数据库的自动更新")
+
+type UpdateOptions struct {
+	// Parallel is the max number of databases downloaded at the same time
+	Parallel int
+	// Mirror is a file:// or http(s):// url with the layout of an extracted bundle,
+	// databases are pulled from it instead of their download urls if set
+	Mirror string
+}
+
+type updateFunc func(progress common.ProgressFunc) error
+
+type updateTask struct {
+	update   updateFunc
+	progress *progress.Task
+}
+
+// UpdateDB downloads the given databases in parallel and prints a summary,
+// an error is returned if any of them failed
+func UpdateDB(opts UpdateOptions, dbNames ...string) error {
+	if opts.Parallel <= 0 {
+		opts.Parallel = DefaultUpdateParallel
+	}
+
+	getUpdateFunc := getUpdateFuncByName
+	if opts.Mirror != "" {
+		manifest, err := fetchManifest(opts.Mirror)
+		if err != nil {
+			return fmt.Errorf("mirror %s 不可用: %v", opts.Mirror, err)
+		}
+		if len(dbNames) == 0 {
+			for _, entry := range manifest.Databases {
+				dbNames = append(dbNames, entry.Name)
+			}
+		}
+		getUpdateFunc = func(name string) (updateFunc, string) {
+			return getMirrorUpdateFunc(opts.Mirror, manifest, name)
+		}
+	}
 	if len(dbNames) == 0 {
 		dbNames = DbNameListForUpdate
 	}
 
+	// progress and logs go to stderr, only the summary is written to stdout
+	board := progress.NewBoard(os.Stderr)
+	var tasks []*updateTask
 	done := make(map[string]struct{})
 	for _, dbName := range dbNames {
-		update, name := getUpdateFuncByName(dbName)
+		update, name := getUpdateFunc(dbName)
 		if _, found := done[name]; !found {
 			done[name] = struct{}{}
-			if err := update(); err != nil {
-				continue
+			tasks = append(tasks, &updateTask{update: update, progress: board.Add(name)})
+		}
+	}
+
+	board.Start()
+	sem := make(chan struct{}, opts.Parallel)
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(task *updateTask) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			task.progress.Begin()
+			err := task.update(task.progress.Update)
+			if errors.Is(err, errUpdateSkipped) {
+				task.progress.Skip(err)
+			} else {
+				task.progress.Finish(err)
 			}
+		}(task)
+	}
+	wg.Wait()
+	board.Stop()
+
+	return printUpdateSummary(tasks)
+}
+
+func printUpdateSummary(tasks []*updateTask) error {
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "\nDB\tSTATUS\tSIZE\tTIME\tINFO")
+	for _, task := range tasks {
+		p := task.progress
+		switch p.State() {
+		case progress.StateDone:
+			_, _ = fmt.Fprintf(w, "%s\t成功\t%s\t%s\t\n", p.Name, progress.FormatBytes(p.Size()), p.Elapsed())
+		case progress.StateSkipped:
+			_, _ = fmt.Fprintf(w, "%s\t跳过\t-\t-\t%v\n", p.Name, p.Err())
+		default:
+			failed++
+			_, _ = fmt.Fprintf(w, "%s\t失败\t-\t%s\t%v\n", p.Name, p.Elapsed(), p.Err())
 		}
 	}
+	_ = w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d 个数据库更新失败", failed)
+	}
+	return nil
 }
 
 var DbNameListForUpdate = []string{
@@ -35,74 +138,80 @@ var DbNameListForUpdate = []string{
 	"zxipv6wry",
 	"ip2region",
 	"cdn",
+	"psl",
 }
 
 var DbCheckFunc = map[Format]func([]byte) bool{
-	FormatQQWry:     qqwry.CheckFile,
-	FormatZXIPv6Wry: zxipv6wry.CheckFile,
+	FormatQQWry:       qqwry.CheckFile,
+	FormatZXIPv6Wry:   zxipv6wry.CheckFile,
+	FormatMMDB:        geoip.CheckFile,
+	FormatIP2Location: ip2location.CheckFile,
+	FormatPSL:         psl.CheckFile,
 }
 
-func getUpdateFuncByName(name string) (func() error, string) {
+func getUpdateFuncByName(name string) (updateFunc, string) {
 	name = strings.TrimSpace(name)
-	if db := getDbByName(name); db != nil {
+	if db := findDbByName(name, true); db != nil {
 		// direct download if download-url not null
 		if len(db.DownloadUrls) > 0 {
-			return func() error {
+			return func(progress common.ProgressFunc) error {
 				log.Printf("正在下载最新 %s 数据库...\n", db.Name)
-				data, err := download.Download(db.File, db.DownloadUrls...)
+				_, err := download.DownloadWithOptions(db.File, download.Options{
+					Progress: progress,
+					Archive:  archive.Type(db.Archive),
+					Member:   db.Member,
+					Check:    DbCheckFunc[db.Format],
+				}, db.DownloadUrls...)
 				if err != nil {
-					log.Printf("❌ %s 数据库下载失败！\n", db.Name)
-				log.Printf("📁 请手动下载并保存到: %s\n", filepath.Join(constant.DataDirPath, db.File))
-					log.Printf("🔗 下载地址: %v\n", db.DownloadUrls)
-				log.Printf("💡 操作步骤:\n")
-				log.Printf("   1. 从上述链接下载文件\n")
-				log.Printf("   2. 将下载的文件重命名为: %s\n", db.File)
-				log.Printf("   3. 复制到数据目录: %s\n", constant.DataDirPath)
-				log.Printf("   4. 重新运行 nabili\n")
-					log.Println("error:", err)
+					logManualDownload(db)
 					return err
-				} else {
-					if check, ok := DbCheckFunc[db.Format]; ok {
-						if !check(data) {
-							log.Printf("❌ %s 数据库下载失败！\n", db.Name)
-				log.Printf("📁 请手动下载并保存到: %s\n", filepath.Join(constant.DataDirPath, db.File))
-							log.Printf("🔗 下载地址: %v\n", db.DownloadUrls)
-				log.Printf("💡 操作步骤:\n")
-				log.Printf("   1. 从上述链接下载文件\n")
-				log.Printf("   2. 将下载的文件重命名为: %s\n", db.File)
-				log.Printf("   3. 复制到数据目录: %s\n", constant.DataDirPath)
-				log.Printf("   4. 重新运行 nabili\n")
-							return errors.New("数据库内容出错")
-						}
-					}
-					log.Printf("%s 数据库下载成功: %s\n", db.Name, db.File)
-					return nil
 				}
-			}, string(db.Format)
+				log.Printf("%s 数据库下载成功: %s\n", db.Name, db.File)
+				return nil
+			}, db.Name
 		}
 
 		// intenel download func
 		switch db.Format {
-		case FormatZXIPv6Wry:
-			return func() error {
-				log.Println("正在下载最新 ZX IPv6数据库...")
-				_, err := zxipv6wry.Download(getDbByName("zxipv6wry").File)
+		case FormatMMDB:
+			return func(progress common.ProgressFunc) error {
+				log.Printf("正在下载最新 %s 数据库...\n", db.Name)
+				_, err := geoip.DownloadMaxMind(db.File, viper.GetString("maxmind.account-id"), viper.GetString("maxmind.license-key"), progress)
+				if err != nil {
+					log.Printf("数据库 %s 下载失败: %v\n", db.Name, err)
+				}
+				return err
+			}, db.Name
+		case FormatIP2Location:
+			return func(progress common.ProgressFunc) error {
+				log.Printf("正在下载最新 %s 数据库...\n", db.Name)
+				_, err := ip2location.Download(db.File, viper.GetString("ip2location.token"), viper.GetString("ip2location.file-code"), progress)
 				if err != nil {
-					log.Println("数据库 ZXIPv6Wry 下载失败:", err)
+					log.Printf("数据库 %s 下载失败: %v\n", db.Name, err)
 				}
 				return err
-			}, FormatZXIPv6Wry
+			}, db.Name
 		default:
-			return func() error {
-				log.Println("暂不支持该类型数据库的自动更新")
+			return func(progress common.ProgressFunc) error {
+				log.Printf("%s: 暂不支持该类型数据库的自动更新\n", db.Name)
 				log.Println("可通过指定数据库的 download-urls 从特定链接下载数据库文件")
-				return nil
-			}, time.Now().String()
+				return errUpdateSkipped
+			}, db.Name
 		}
 	} else {
-		return func() error {
-			log.Fatalln("该名称的数据库未找到：", name)
-			return nil
-		}, time.Now().String()
+		return func(progress common.ProgressFunc) error {
+			return fmt.Errorf("该名称的数据库未找到：%s", name)
+		}, name
 	}
 }
+
+func logManualDownload(db *DB) {
+	log.Printf("❌ %s 数据库下载失败！\n", db.Name)
+	log.Printf("📁 请手动下载并保存到: %s\n", filepath.Join(constant.DataDirPath, db.File))
+	log.Printf("🔗 下载地址: %v\n", db.DownloadUrls)
+	log.Printf("💡 操作步骤:\n")
+	log.Printf("   1. 从上述链接下载文件\n")
+	log.Printf("   2. 将下载的文件重命名为: %s\n", db.File)
+	log.Printf("   3. 复制到数据目录: %s\n", constant.DataDirPath)
+	log.Printf("   4. 重新运行 nabili\n")
+}
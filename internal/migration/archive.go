@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"log"
+
+	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/internal/db"
+	"github.com/abc1763613206/nabili/pkg/geoip"
+	"github.com/abc1763613206/nabili/pkg/zxipv6wry"
+)
+
+// migrationArchive fills in download-urls and archive for zxipv6wry and dbip,
+// which used to be downloaded by built-in code
+func migrationArchive() {
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath(constant.ConfigDirPath)
+
+	err := viper.ReadInConfig()
+	if err != nil {
+		return
+	}
+
+	dbList := db.List{}
+	err = viper.UnmarshalKey("databases", &dbList)
+	if err != nil {
+		log.Fatalln("Config invalid:", err)
+	}
+
+	needOverwrite := false
+	for _, adb := range dbList {
+		if len(adb.DownloadUrls) > 0 {
+			continue
+		}
+		switch {
+		case adb.Format == db.FormatZXIPv6Wry:
+			needOverwrite = true
+			adb.DownloadUrls = zxipv6wry.DownloadUrls
+			adb.Archive = string(zxipv6wry.Archive)
+			adb.Member = zxipv6wry.Member
+		case adb.Name == "dbip" && adb.Format == db.FormatMMDB:
+			needOverwrite = true
+			adb.DownloadUrls = geoip.DBIPDownloadUrls
+			adb.Archive = string(geoip.DBIPArchive)
+		}
+	}
+
+	if needOverwrite {
+		viper.Set("databases", dbList)
+		err = viper.WriteConfig()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -3,4 +3,5 @@ package migration
 func init() {
 	migration2v6()
 	migration2v7()
+	migrationArchive()
 }
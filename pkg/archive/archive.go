@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/saracen/go7z"
+	"github.com/ulikunitz/xz"
+)
+
+// Type of the compressed upstream file
+type Type string
+
+const (
+	TypeNone  Type = ""
+	TypeZip   Type = "zip"
+	TypeGz    Type = "gz"
+	TypeTarGz Type = "tar.gz"
+	Type7z    Type = "7z"
+	TypeXz    Type = "xz"
+	TypeTarXz Type = "tar.xz"
+)
+
+// Extract returns the content of member in the archive data.
+// member can be a full path, a base name or a path.Match pattern,
+// the first regular file is returned if member is empty.
+// gz and xz hold a single file so member is ignored.
+func Extract(data []byte, typ Type, member string) ([]byte, error) {
+	switch Type(strings.ToLower(string(typ))) {
+	case TypeNone:
+		return data, nil
+	case TypeGz:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress gzip file: %v", err)
+		}
+		return readAll(r, "gzip")
+	case TypeXz:
+		r, err := xz.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress xz file: %v", err)
+		}
+		return readAll(r, "xz")
+	case TypeTarGz, "tgz":
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress gzip file: %v", err)
+		}
+		return untar(r, member)
+	case TypeTarXz:
+		r, err := xz.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress xz file: %v", err)
+		}
+		return untar(r, member)
+	case TypeZip:
+		return unzip(data, member)
+	case Type7z:
+		return un7z(data, member)
+	default:
+		return nil, fmt.Errorf("archive type %s not supported", typ)
+	}
+}
+
+// TypeFromName guesses the archive type from a file name or url
+func TypeFromName(name string) Type {
+	name = strings.ToLower(name)
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return TypeTarGz
+	case strings.HasSuffix(name, ".tar.xz"):
+		return TypeTarXz
+	case strings.HasSuffix(name, ".gz"):
+		return TypeGz
+	case strings.HasSuffix(name, ".xz"):
+		return TypeXz
+	case strings.HasSuffix(name, ".zip"):
+		return TypeZip
+	case strings.HasSuffix(name, ".7z"):
+		return Type7z
+	}
+	return TypeNone
+}
+
+func match(member, name string) bool {
+	if member == "" || member == name || path.Base(name) == member {
+		return true
+	}
+	ok, _ := path.Match(member, name)
+	return ok
+}
+
+func readAll(r io.Reader, typ string) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %s file: %v", typ, err)
+	}
+	return data, nil
+}
+
+func untar(r io.Reader, member string) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress tar file: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && match(member, hdr.Name) {
+			return readAll(tr, "tar")
+		}
+	}
+	return nil, fmt.Errorf("%s not found in tar file", member)
+}
+
+func unzip(data []byte, member string) ([]byte, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress zip file: %v", err)
+	}
+
+	for _, file := range z.File {
+		if file.FileInfo().IsDir() || !match(member, file.Name) {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress zip file: %v", err)
+		}
+		defer r.Close()
+		return readAll(r, "zip")
+	}
+	return nil, fmt.Errorf("%s not found in zip file", member)
+}
+
+func un7z(data []byte, member string) ([]byte, error) {
+	sz, err := go7z.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress 7z file: %v", err)
+	}
+
+	for {
+		hdr, err := sz.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress 7z file: %v", err)
+		}
+
+		if !hdr.IsEmptyStream && !hdr.IsEmptyFile && match(member, hdr.Name) {
+			return readAll(sz, "7z")
+		}
+		// entries of a solid archive must be read in order
+		if _, err := io.Copy(io.Discard, sz); err != nil {
+			return nil, fmt.Errorf("cannot decompress 7z file: %v", err)
+		}
+	}
+	return nil, fmt.Errorf("%s not found in 7z file", member)
+}
@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+var content = []byte("nabili archive test")
+
+func gzipData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func xzData(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarData(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	files := map[string][]byte{
+		"GeoLite2-City_20240101/LICENSE.txt":        []byte("license"),
+		"GeoLite2-City_20240101/GeoLite2-City.mmdb": content,
+	}
+	for _, name := range []string{"GeoLite2-City_20240101/LICENSE.txt", "GeoLite2-City_20240101/GeoLite2-City.mmdb"} {
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipData(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{
+		"README_LITE.TXT":               []byte("readme"),
+		"IP2LOCATION-LITE-DB3.IPV6.BIN": content,
+	} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		typ     Type
+		member  string
+		wantErr bool
+	}{
+		{"none", content, TypeNone, "", false},
+		{"gz", gzipData(t, content), TypeGz, "", false},
+		{"xz", xzData(t, content), TypeXz, "", false},
+		{"tar.gz by base name", gzipData(t, tarData(t)), TypeTarGz, "GeoLite2-City.mmdb", false},
+		{"tar.gz by pattern", gzipData(t, tarData(t)), TypeTarGz, "*/GeoLite2-City.mmdb", false},
+		{"tar.xz by full path", xzData(t, tarData(t)), TypeTarXz, "GeoLite2-City_20240101/GeoLite2-City.mmdb", false},
+		{"tar.gz missing member", gzipData(t, tarData(t)), TypeTarGz, "GeoLite2-ASN.mmdb", true},
+		{"zip by base name", zipData(t), TypeZip, "IP2LOCATION-LITE-DB3.IPV6.BIN", false},
+		{"zip by pattern", zipData(t), TypeZip, "*.BIN", false},
+		{"zip missing member", zipData(t), TypeZip, "ipv6wry.db", true},
+		{"corrupted gz", content, TypeGz, "", true},
+		{"unknown type", content, "rar", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.data, tt.typ, tt.member)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, content) {
+				t.Errorf("Extract() got = %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestTypeFromName(t *testing.T) {
+	tests := map[string]Type{
+		"nali-linux-amd64-v0.8.0.gz":    TypeGz,
+		"nali-windows-amd64-v0.8.0.zip": TypeZip,
+		"https://ip.zxinc.org/ip.7z":    Type7z,
+		"GeoLite2-City.tar.gz":          TypeTarGz,
+		"https://download.maxmind.com/geoip/databases/GeoLite2-City/download?suffix=tar.gz": TypeNone,
+		"qqwry.dat": TypeNone,
+	}
+	for name, want := range tests {
+		if got := TypeFromName(name); got != want {
+			t.Errorf("TypeFromName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
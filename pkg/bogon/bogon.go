@@ -0,0 +1,120 @@
+// Package bogon classifies special-purpose addresses from the IANA IPv4 and IPv6
+// Special-Purpose Address Registries and the multicast ranges.
+// Such addresses are not located by geolocation databases.
+package bogon
+
+import (
+	"net/netip"
+)
+
+// Class is a special-purpose address block
+type Class struct {
+	Name   string       `json:"class"`
+	Prefix netip.Prefix `json:"prefix"`
+	RFC    string       `json:"rfc"`
+
+	zh string
+	en string
+}
+
+// Text returns the description of the class in lang, zh-CN or en
+func (c Class) Text(lang string) string {
+	if lang == "zh-CN" || lang == "" {
+		return c.zh
+	}
+	return c.en
+}
+
+// Result is the lookup result of a special-purpose address
+type Result struct {
+	Class
+	Text string `json:"text"`
+}
+
+func (r Result) String() string {
+	return r.Text
+}
+
+func class(prefix, name, rfc, zh, en string) Class {
+	return Class{Name: name, Prefix: netip.MustParsePrefix(prefix), RFC: rfc, zh: zh, en: en}
+}
+
+// Registry lists the blocks, longer prefixes take precedence
+var Registry = []Class{
+	class("0.0.0.0/8", "this-network", "RFC791", "本网络", "This network"),
+	class("0.0.0.0/32", "this-host", "RFC1122", "本机", "This host"),
+	class("10.0.0.0/8", "private", "RFC1918", "私有地址", "Private-Use"),
+	class("100.64.0.0/10", "shared", "RFC6598", "运营商级 NAT 共享地址", "Shared Address Space"),
+	class("127.0.0.0/8", "loopback", "RFC1122", "本机环回地址", "Loopback"),
+	class("169.254.0.0/16", "link-local", "RFC3927", "链路本地地址", "Link Local"),
+	class("172.16.0.0/12", "private", "RFC1918", "私有地址", "Private-Use"),
+	class("192.0.0.0/24", "ietf-protocol", "RFC6890", "IETF 协议分配地址", "IETF Protocol Assignments"),
+	class("192.0.0.0/29", "ipv4-service-continuity", "RFC7335", "IPv4 服务连续性前缀", "IPv4 Service Continuity Prefix"),
+	class("192.0.0.8/32", "dummy", "RFC7600", "IPv4 占位地址", "IPv4 dummy address"),
+	class("192.0.0.170/31", "nat64-discovery", "RFC7050", "NAT64/DNS64 发现地址", "NAT64/DNS64 Discovery"),
+	class("192.0.2.0/24", "documentation", "RFC5737", "文档示例地址", "Documentation (TEST-NET-1)"),
+	class("192.88.99.0/24", "6to4-relay", "RFC7526", "6to4 中继任播地址", "Deprecated 6to4 Relay Anycast"),
+	class("192.168.0.0/16", "private", "RFC1918", "私有地址", "Private-Use"),
+	class("198.18.0.0/15", "benchmarking", "RFC2544", "基准测试地址", "Benchmarking"),
+	class("198.51.100.0/24", "documentation", "RFC5737", "文档示例地址", "Documentation (TEST-NET-2)"),
+	class("203.0.113.0/24", "documentation", "RFC5737", "文档示例地址", "Documentation (TEST-NET-3)"),
+	class("224.0.0.0/4", "multicast", "RFC5771", "组播地址", "Multicast"),
+	class("240.0.0.0/4", "reserved", "RFC1112", "保留地址", "Reserved"),
+	class("255.255.255.255/32", "broadcast", "RFC919", "受限广播地址", "Limited Broadcast"),
+
+	class("::/128", "unspecified", "RFC4291", "未指定地址", "Unspecified Address"),
+	class("::1/128", "loopback", "RFC4291", "本机环回地址", "Loopback Address"),
+	class("::ffff:0:0/96", "ipv4-mapped", "RFC4291", "IPv4 映射地址", "IPv4-mapped Address"),
+	class("64:ff9b:1::/48", "nat64-local", "RFC8215", "本地 IPv4/IPv6 转换地址", "IPv4-IPv6 Translation"),
+	class("100::/64", "discard", "RFC6666", "丢弃前缀", "Discard-Only Address Block"),
+	class("2001::/23", "ietf-protocol", "RFC2928", "IETF 协议分配地址", "IETF Protocol Assignments"),
+	class("2001::/32", "teredo", "RFC4380", "Teredo 隧道地址", "TEREDO"),
+	class("2001:2::/48", "benchmarking", "RFC5180", "基准测试地址", "Benchmarking"),
+	class("2001:10::/28", "orchid", "RFC4843", "ORCHID 地址", "Deprecated (previously ORCHID)"),
+	class("2001:20::/28", "orchid", "RFC7343", "ORCHIDv2 地址", "ORCHIDv2"),
+	class("2001:db8::/32", "documentation", "RFC3849", "文档示例地址", "Documentation"),
+	class("2002::/16", "6to4", "RFC3056", "6to4 隧道地址", "6to4"),
+	class("3fff::/20", "documentation", "RFC9637", "文档示例地址", "Documentation"),
+	class("5f00::/16", "srv6", "RFC9602", "SRv6 SID", "Segment Routing (SRv6) SIDs"),
+	class("fc00::/7", "unique-local", "RFC4193", "唯一本地地址", "Unique-Local"),
+	class("fe80::/10", "link-local", "RFC4291", "链路本地地址", "Link-Local Unicast"),
+	class("ff00::/8", "multicast", "RFC4291", "组播地址", "Multicast"),
+}
+
+// Classify returns the most specific special-purpose block containing ip
+func Classify(ip netip.Addr) (Class, bool) {
+	ip = ip.WithZone("")
+	var match Class
+	found := false
+	for _, c := range Registry {
+		if c.Prefix.Contains(ip) && (!found || c.Prefix.Bits() > match.Prefix.Bits()) {
+			match, found = c, true
+		}
+	}
+	return match, found
+}
+
+// IsBogon reports whether ip is a special-purpose address
+func IsBogon(ip netip.Addr) bool {
+	_, found := Classify(ip)
+	return found
+}
+
+// localClasses are the classes of addresses only meaningful inside a network or a host
+var localClasses = map[string]bool{
+	"this-network": true,
+	"this-host":    true,
+	"private":      true,
+	"shared":       true,
+	"loopback":     true,
+	"link-local":   true,
+	"unspecified":  true,
+	"unique-local": true,
+}
+
+// IsPrivate reports whether ip is a private, shared, loopback, link-local,
+// unique-local or unspecified address, IPv4-mapped addresses are unmapped first
+func IsPrivate(ip netip.Addr) bool {
+	class, found := Classify(ip.Unmap())
+	return found && localClasses[class.Name]
+}
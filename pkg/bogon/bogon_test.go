@@ -0,0 +1,75 @@
+package bogon
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"10.1.2.3", "private"},
+		{"172.31.255.255", "private"},
+		{"172.32.0.1", ""},
+		{"100.64.0.1", "shared"},
+		{"127.0.0.1", "loopback"},
+		{"169.254.1.1", "link-local"},
+		{"192.0.0.8", "dummy"},
+		{"192.0.0.100", "ietf-protocol"},
+		{"198.51.100.7", "documentation"},
+		{"239.255.255.250", "multicast"},
+		{"255.255.255.255", "broadcast"},
+		{"0.0.0.0", "this-host"},
+		{"8.8.8.8", ""},
+		{"::", "unspecified"},
+		{"::1", "loopback"},
+		{"::ffff:8.8.8.8", "ipv4-mapped"},
+		{"2001:0:4136:e378:8000:63bf:3fff:fdd2", "teredo"},
+		{"2001:1::3", "ietf-protocol"},
+		{"2001:20::1", "orchid"},
+		{"2001:db8::1", "documentation"},
+		{"2002:c000:204::1", "6to4"},
+		{"fd12:3456::1", "unique-local"},
+		{"fe80::1%eth0", "link-local"},
+		{"ff02::1", "multicast"},
+		{"2400:3200::1", ""},
+	}
+	for _, tt := range tests {
+		class, found := Classify(netip.MustParseAddr(tt.ip))
+		if found != (tt.want != "") || class.Name != tt.want {
+			t.Errorf("Classify(%s) = %q %v, want %q", tt.ip, class.Name, found, tt.want)
+		}
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"10.0.0.1":         true,
+		"100.64.0.1":       true,
+		"127.0.0.1":        true,
+		"::ffff:192.0.2.1": false,
+		"::ffff:10.0.0.1":  true,
+		"fe80::1%eth0":     true,
+		"fd00::1":          true,
+		"192.0.2.1":        false,
+		"224.0.0.1":        false,
+		"8.8.8.8":          false,
+	} {
+		if got := IsPrivate(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("IsPrivate(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	for _, c := range Registry {
+		if c.Prefix != c.Prefix.Masked() {
+			t.Errorf("%s is not masked", c.Prefix)
+		}
+		if c.Text("zh-CN") == "" || c.Text("en") == "" {
+			t.Errorf("%s has no description", c.Prefix)
+		}
+	}
+}
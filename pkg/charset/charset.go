@@ -0,0 +1,265 @@
+// Package charset decodes input lines from the encodings found in logs of
+// Chinese and Windows hosts and encodes output to them, on top of golang.org/x/text
+package charset
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+// Auto is the name for detecting the encoding, see Scanner
+const Auto = "auto"
+
+// Names are the encodings accepted by Lookup
+var Names = []string{Auto, "utf-8", "gbk", "gb18030", "big5", "utf-16le", "utf-16be"}
+
+var (
+	utf16LE = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	utf16BE = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+)
+
+var encodings = map[string]encoding.Encoding{
+	"utf-8":    unicode.UTF8,
+	"utf8":     unicode.UTF8,
+	"gbk":      simplifiedchinese.GBK,
+	"cp936":    simplifiedchinese.GBK,
+	"gb18030":  simplifiedchinese.GB18030,
+	"big5":     traditionalchinese.Big5,
+	"cp950":    traditionalchinese.Big5,
+	"utf-16le": utf16LE,
+	"utf16le":  utf16LE,
+	"utf-16be": utf16BE,
+	"utf16be":  utf16BE,
+}
+
+// Lookup returns the encoding by name, nil for Auto
+func Lookup(name string) (encoding.Encoding, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == Auto || name == "" {
+		return nil, nil
+	}
+	if enc, found := encodings[name]; found {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("未知的编码 %s，可选 %s", name, strings.Join(Names, ", "))
+}
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// Detect returns the encoding of data, the start of a stream, by its byte order mark,
+// or UTF-16 if most of its ASCII characters have a zero byte before or after them.
+// It returns nil if the encoding has to be detected line by line.
+func Detect(data []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return unicode.UTF8
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return utf16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return utf16BE
+	}
+
+	units := len(data) / 2
+	if units < 2 {
+		return nil
+	}
+	zeros := [2]int{}
+	for i := 0; i < units*2; i++ {
+		if data[i] == 0 {
+			zeros[i%2]++
+		}
+	}
+	switch {
+	case zeros[1]*2 > units && zeros[0]*4 < units:
+		return utf16LE
+	case zeros[0]*2 > units && zeros[1]*4 < units:
+		return utf16BE
+	}
+	return nil
+}
+
+// DecodeLine decodes a line of the encoding, a line of unknown encoding is
+// taken as UTF-8 if it is valid UTF-8 and as GB18030, the superset of GBK, otherwise.
+// Bytes that cannot be decoded are replaced by U+FFFD.
+func DecodeLine(line []byte, enc encoding.Encoding) string {
+	switch {
+	case enc == unicode.UTF8 || (enc == nil && utf8.Valid(line)):
+		return string(line)
+	case enc == nil:
+		enc = simplifiedchinese.GB18030
+	}
+	text, err := enc.NewDecoder().Bytes(line)
+	if err != nil {
+		return string(line)
+	}
+	return string(text)
+}
+
+// Scanner reads the lines of a stream in an encoding like bufio.Scanner with common.ScanLines,
+// Bytes returns the line as read and Text the line decoded to UTF-8
+type Scanner struct {
+	*bufio.Scanner
+
+	r        *bufio.Reader
+	enc      encoding.Encoding
+	detect   bool
+	detected bool
+	lines    int
+	// lineEnc is set once a line is not valid UTF-8 when detecting line by line
+	lineEnc encoding.Encoding
+}
+
+// NewScanner returns a scanner of r in enc, with nil the encoding is detected by Detect
+// at the first line and the lines are decoded one by one if that finds nothing
+func NewScanner(r io.Reader, enc encoding.Encoding) *Scanner {
+	s := &Scanner{r: bufio.NewReader(r), enc: enc, detect: enc == nil}
+	s.Scanner = bufio.NewScanner(s.r)
+	s.Scanner.Split(s.split)
+	return s
+}
+
+// Encoding returns the encoding of the stream, nil if it is detected line by line.
+// It waits for the start of the stream if the encoding is not detected yet.
+func (s *Scanner) Encoding() encoding.Encoding {
+	if s.detect && !s.detected {
+		s.detected = true
+		// take whatever the first read returns, a pipe may not fill the buffer for a while
+		_, _ = s.r.Peek(1)
+		data, _ := s.r.Peek(s.r.Buffered())
+		s.enc = Detect(data)
+	}
+	return s.enc
+}
+
+// LineEncoding returns the encoding of the lines read so far, that is the encoding
+// of the stream, or if that is detected line by line, GB18030 once a line was not
+// valid UTF-8 and UTF-8 before
+func (s *Scanner) LineEncoding() encoding.Encoding {
+	switch {
+	case s.Encoding() != nil:
+		return s.enc
+	case s.lineEnc != nil:
+		return s.lineEnc
+	}
+	return unicode.UTF8
+}
+
+func (s *Scanner) Scan() bool {
+	s.Encoding()
+	s.lines++
+	return s.Scanner.Scan()
+}
+
+// Text returns the last line decoded to UTF-8, without the byte order mark of the stream
+func (s *Scanner) Text() string {
+	line := s.Bytes()
+	if s.lines == 1 {
+		for _, bom := range [][]byte{bomUTF8, bomUTF16LE, bomUTF16BE} {
+			if bytes.HasPrefix(line, bom) && Detect(bom) == s.enc {
+				line = line[len(bom):]
+				break
+			}
+		}
+	}
+	if s.enc == nil && s.lineEnc == nil && !utf8.Valid(line) {
+		s.lineEnc = simplifiedchinese.GB18030
+	}
+	return DecodeLine(line, s.enc)
+}
+
+// split is common.ScanLines on the code units of UTF-16
+func (s *Scanner) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	var bigEndian bool
+	switch s.enc {
+	case utf16LE:
+	case utf16BE:
+		bigEndian = true
+	default:
+		return common.ScanLines(data, atEOF)
+	}
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	unit := func(i int) byte {
+		if bigEndian {
+			if data[i] != 0 {
+				return 0xff
+			}
+			return data[i+1]
+		}
+		if data[i+1] != 0 {
+			return 0xff
+		}
+		return data[i]
+	}
+	cr := -1
+	for i := 0; i+1 < len(data); i += 2 {
+		switch unit(i) {
+		case '\n':
+			return i + 2, data[:i+2], nil
+		case '\r':
+			if cr < 0 {
+				cr = i
+			}
+		}
+	}
+	if cr >= 0 {
+		return cr + 2, data[:cr+2], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// NewWriter returns a writer encoding the UTF-8 written to it in enc to w,
+// w itself for UTF-8 and nil. Characters enc does not have are replaced.
+func NewWriter(w io.Writer, enc encoding.Encoding) io.Writer {
+	if enc == nil || enc == unicode.UTF8 {
+		return w
+	}
+	return NewFuncWriter(w, func() encoding.Encoding { return enc })
+}
+
+// NewFuncWriter is NewWriter with the encoding returned by enc at every write,
+// like Scanner.LineEncoding to write in the encoding of the input
+func NewFuncWriter(w io.Writer, enc func() encoding.Encoding) io.Writer {
+	return &writer{w: w, enc: enc}
+}
+
+type writer struct {
+	w   io.Writer
+	enc func() encoding.Encoding
+}
+
+// Write encodes p at once, callers write whole strings
+func (w *writer) Write(p []byte) (int, error) {
+	enc := w.enc()
+	if enc == nil || enc == unicode.UTF8 {
+		return w.w.Write(p)
+	}
+	data, err := encoding.ReplaceUnsupported(enc.NewEncoder()).Bytes(p)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
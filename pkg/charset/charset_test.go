@@ -0,0 +1,101 @@
+package charset
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	data, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range Names {
+		if _, err := Lookup(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if enc, _ := Lookup("GBK"); enc != simplifiedchinese.GBK {
+		t.Errorf("Lookup(GBK) = %v", enc)
+	}
+	if _, err := Lookup("latin1"); err == nil {
+		t.Error("Lookup(latin1) should fail")
+	}
+}
+
+func TestScanner(t *testing.T) {
+	lines := "1.1.1.1 北京\r\n8.8.8.8 美國\nend"
+	for _, tt := range []struct {
+		name  string
+		data  []byte
+		enc   encoding.Encoding
+		want  encoding.Encoding
+		lines string
+	}{
+		{"utf-8", []byte(lines), nil, nil, lines},
+		{"utf-8 bom", append([]byte{0xef, 0xbb, 0xbf}, lines...), nil, unicode.UTF8, lines},
+		{"gbk", encode(t, simplifiedchinese.GBK, "1.1.1.1 北京\n"), nil, nil, "1.1.1.1 北京\n"},
+		{"mixed", append([]byte("1.1.1.1 北京\n"), encode(t, simplifiedchinese.GB18030, "2.2.2.2 上海\n")...), nil, nil, "1.1.1.1 北京\n2.2.2.2 上海\n"},
+		{"big5", encode(t, traditionalchinese.Big5, lines), traditionalchinese.Big5, traditionalchinese.Big5, lines},
+		{"utf-16le bom", append([]byte{0xff, 0xfe}, encode(t, utf16LE, lines)...), nil, utf16LE, lines},
+		{"utf-16be bom", append([]byte{0xfe, 0xff}, encode(t, utf16BE, lines)...), nil, utf16BE, lines},
+		{"utf-16le", encode(t, utf16LE, lines), nil, utf16LE, lines},
+		{"utf-16le explicit bom", append([]byte{0xff, 0xfe}, encode(t, utf16LE, lines)...), utf16LE, utf16LE, lines},
+	} {
+		s := NewScanner(bytes.NewReader(tt.data), tt.enc)
+		var got strings.Builder
+		n := 0
+		for s.Scan() {
+			got.WriteString(s.Text())
+			n += len(s.Bytes())
+		}
+		if got.String() != tt.lines {
+			t.Errorf("%s: lines = %q, want %q", tt.name, got.String(), tt.lines)
+		}
+		if s.Encoding() != tt.want {
+			t.Errorf("%s: encoding = %v, want %v", tt.name, s.Encoding(), tt.want)
+		}
+		lineWant := tt.want
+		switch tt.name {
+		case "utf-8":
+			lineWant = unicode.UTF8
+		case "gbk", "mixed":
+			lineWant = simplifiedchinese.GB18030
+		}
+		if s.LineEncoding() != lineWant {
+			t.Errorf("%s: line encoding = %v, want %v", tt.name, s.LineEncoding(), lineWant)
+		}
+		// the raw lines cover the input
+		if n != len(tt.data) {
+			t.Errorf("%s: raw lines have %d bytes, want %d", tt.name, n, len(tt.data))
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, simplifiedchinese.GBK)
+	if _, err := w.Write([]byte("8.8.8.8 [美国 🌐]")); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := simplifiedchinese.GBK.NewDecoder().Bytes(buf.Bytes())
+	if string(got) != "8.8.8.8 [美国 \x1a]" {
+		t.Errorf("GBK output = %q", got)
+	}
+
+	var out bytes.Buffer
+	if NewWriter(&out, unicode.UTF8) != &out {
+		t.Error("UTF-8 output should not be wrapped")
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -9,6 +10,10 @@ import (
 
 const UserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/102.0.0.0 Safari/537.36"
 
+// ProgressFunc is called while a response body is being read,
+// total is -1 if the server did not send a Content-Length
+type ProgressFunc func(current, total int64)
+
 type HttpClient struct {
 	*http.Client
 }
@@ -18,13 +23,16 @@ var httpClient *HttpClient
 func init() {
 	httpClient = &HttpClient{http.DefaultClient}
 	httpClient.Timeout = time.Second * 60
-	httpClient.Transport = &http.Transport{
+	transport := &http.Transport{
 		TLSHandshakeTimeout:   time.Second * 5,
 		IdleConnTimeout:       time.Second * 10,
 		ResponseHeaderTimeout: time.Second * 10,
 		ExpectContinueTimeout: time.Second * 20,
 		Proxy:                 http.ProxyFromEnvironment,
 	}
+	// file:// urls allow download-urls and mirrors on local or mounted dirs
+	transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+	httpClient.Transport = transport
 }
 
 func GetHttpClient() *HttpClient {
@@ -33,6 +41,16 @@ func GetHttpClient() *HttpClient {
 }
 
 func (c *HttpClient) Get(urls ...string) (body []byte, err error) {
+	return c.GetWithProgress(nil, urls...)
+}
+
+// GetWithProgress works like Get but reports the body reading progress
+func (c *HttpClient) GetWithProgress(progress ProgressFunc, urls ...string) (body []byte, err error) {
+	return c.GetWithHeader(nil, progress, urls...)
+}
+
+// GetWithHeader works like GetWithProgress but sends extra request headers, e.g. Authorization
+func (c *HttpClient) GetWithHeader(header http.Header, progress ProgressFunc, urls ...string) (body []byte, err error) {
 	var req *http.Request
 	var resp *http.Response
 
@@ -42,18 +60,43 @@ func (c *HttpClient) Get(urls ...string) (body []byte, err error) {
 			log.Println(err)
 			continue
 		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
 		req.Header.Set("User-Agent", UserAgent)
 		resp, err = c.Do(req)
 
 		if err == nil && resp != nil && resp.StatusCode == 200 {
-			body, err = io.ReadAll(resp.Body)
+			var r io.Reader = resp.Body
+			if progress != nil {
+				r = &progressReader{r: resp.Body, total: resp.ContentLength, progress: progress}
+			}
+			body, err = io.ReadAll(r)
 			_ = resp.Body.Close()
 			if err != nil {
 				continue
 			}
 			return
 		}
+		if err == nil && resp != nil {
+			err = fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+			_ = resp.Body.Close()
+		}
 	}
 
 	return nil, err
 }
+
+type progressReader struct {
+	r        io.Reader
+	current  int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (n int, err error) {
+	n, err = p.r.Read(b)
+	p.current += int64(n)
+	p.progress(p.current, p.total)
+	return
+}
@@ -0,0 +1,28 @@
+package common
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// DisplayWidth is the number of terminal cells of s, East Asian wide characters take two
+func DisplayWidth(s string) int {
+	n := 0
+	for _, c := range s {
+		switch {
+		case c < utf8.RuneSelf:
+			n++
+		case unicode.Is(unicode.Mn, c):
+		default:
+			switch width.LookupRune(c).Kind() {
+			case width.EastAsianWide, width.EastAsianFullwidth:
+				n += 2
+			default:
+				n++
+			}
+		}
+	}
+	return n
+}
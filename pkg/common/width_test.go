@@ -0,0 +1,11 @@
+package common
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	for s, want := range map[string]int{"abc": 3, "中国": 4, "ｱ": 1, "Ｆ": 2, "é": 1} {
+		if got := DisplayWidth(s); got != want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/abc1763613206/nabili/pkg/ip2location"
 	"github.com/abc1763613206/nabili/pkg/ip2region"
 	"github.com/abc1763613206/nabili/pkg/ipip"
+	"github.com/abc1763613206/nabili/pkg/nbdb"
+	"github.com/abc1763613206/nabili/pkg/overlay"
+	"github.com/abc1763613206/nabili/pkg/psl"
 	"github.com/abc1763613206/nabili/pkg/qqwry"
 	"github.com/abc1763613206/nabili/pkg/zxipv6wry"
 )
@@ -32,5 +35,8 @@ var (
 	_ DB = &geoip.GeoIP{}
 	_ DB = &ip2region.Ip2Region{}
 	_ DB = &ip2location.IP2Location{}
+	_ DB = &nbdb.NBDB{}
+	_ DB = &overlay.Overlay{}
 	_ DB = &cdn.CDN{}
+	_ DB = &psl.List{}
 )
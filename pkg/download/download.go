@@ -2,19 +2,89 @@ package download
 
 import (
 	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/abc1763613206/nabili/pkg/archive"
 	"github.com/abc1763613206/nabili/pkg/common"
 )
 
+var ErrCheckFailed = errors.New("数据库内容出错")
+
+// Options controls how a database file is fetched
+type Options struct {
+	// Progress is called while the file is being downloaded
+	Progress common.ProgressFunc
+	// Header is sent with every request, e.g. Authorization
+	Header http.Header
+
+	// Archive is the compression of the upstream file, Member the file to extract from it
+	Archive archive.Type
+	Member  string
+
+	// Check validates the extracted file before it is saved
+	Check func([]byte) bool
+}
+
 func Download(filePath string, urls ...string) (data []byte, err error) {
+	return DownloadWithOptions(filePath, Options{}, urls...)
+}
+
+// DownloadWithOptions downloads the first available url, extracts it and saves it to filePath.
+// {yyyy} and {mm} in urls are replaced by the current month, and by the last month if that fails.
+func DownloadWithOptions(filePath string, opts Options, urls ...string) (data []byte, err error) {
 	if len(urls) == 0 {
 		return nil, errors.New("未指定下载 url")
 	}
 
-	data, err = common.GetHttpClient().Get(urls...)
+	data, err = fetch(opts, urls)
 	if err != nil {
 		return
 	}
 
+	data, err = archive.Extract(data, opts.Archive, opts.Member)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Check != nil && !opts.Check(data) {
+		return nil, ErrCheckFailed
+	}
+
 	err = common.SaveFile(filePath, data)
 	return
 }
+
+func fetch(opts Options, urls []string) (data []byte, err error) {
+	client := common.GetHttpClient()
+
+	templated := false
+	for _, url := range urls {
+		if strings.Contains(url, "{yyyy}") || strings.Contains(url, "{mm}") {
+			templated = true
+		}
+	}
+	if !templated {
+		return client.GetWithHeader(opts.Header, opts.Progress, urls...)
+	}
+
+	// monthly releases may not be published yet at the beginning of a month
+	now := time.Now().UTC()
+	for _, month := range []time.Time{now, now.AddDate(0, 0, -now.Day())} {
+		data, err = client.GetWithHeader(opts.Header, opts.Progress, ExpandMonth(urls, month)...)
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
+// ExpandMonth replaces {yyyy} and {mm} in the url templates with the given month
+func ExpandMonth(urls []string, month time.Time) []string {
+	replacer := strings.NewReplacer("{yyyy}", month.Format("2006"), "{mm}", month.Format("01"))
+	expanded := make([]string, 0, len(urls))
+	for _, url := range urls {
+		expanded = append(expanded, replacer.Replace(url))
+	}
+	return expanded
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/abc1763613206/nabili/pkg/dbif"
+	"github.com/abc1763613206/nabili/pkg/iprange"
+	"github.com/abc1763613206/nabili/pkg/transition"
 )
 
 type EntityType uint
@@ -23,10 +25,33 @@ type Entity struct {
 	Loc  [2]int     `json:"-"` // s[Loc[0]:Loc[1]]
 	Type EntityType `json:"type"`
 
-	Text     string      `json:"ip"`
-	InfoText string      `json:"text"`
-	Source   string      `json:"source"`
-	Info     interface{} `json:"info"`
+	Text string `json:"ip"`
+	// Token is the whole token at Loc if it is more than the host in Text,
+	// like 1.2.3.4:443, 10.0.0.0/8 or a URL
+	Token  string `json:"-"`
+	Port   uint16 `json:"port,omitempty"`
+	Prefix *int   `json:"prefix,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Domain is the registrable domain of domain entities in xn-- form,
+	// like example.co.uk for www.example.co.uk
+	Domain string `json:"domain,omitempty"`
+
+	InfoText string `json:"text"`
+	Source   string `json:"source"`
+	Class    string `json:"class,omitempty"`
+	// Transition holds the outer and the embedded IPv4 address of transition addresses
+	Transition *transition.Embedded `json:"transition,omitempty"`
+	Info       interface{}          `json:"info"`
+	// Fields are the location fields normalized from Info
+	Fields iprange.Fields `json:"-"`
+}
+
+// token returns the whole text at Loc
+func (e Entity) token() string {
+	if e.Token != "" {
+		return e.Token
+	}
+	return e.Text
 }
 
 func (e Entity) ParseInfo() error {
@@ -58,7 +83,7 @@ func (es Entities) Swap(i, j int) {
 func (es Entities) String() string {
 	var result strings.Builder
 	for _, entity := range es {
-		result.WriteString(entity.Text)
+		result.WriteString(entity.token())
 		if entity.Type != TypePlain && len(entity.InfoText) > 0 {
 			result.WriteString("[" + entity.InfoText + "] ")
 		}
@@ -69,16 +94,8 @@ func (es Entities) String() string {
 func (es Entities) ColorString() string {
 	var line strings.Builder
 	for _, e := range es {
-		s := e.Text
-		switch e.Type {
-		case TypeIPv4:
-			s = color.GreenString(e.Text)
-		case TypeIPv6:
-			s = color.BlueString(e.Text)
-		case TypeDomain:
-			s = color.YellowString(e.Text)
-		}
-		if e.Type != TypePlain && len(e.InfoText) > 0 {
+		s := e.colorToken()
+		if e.annotated() {
 			s += " [" + color.RedString(e.InfoText) + "] "
 		}
 		line.WriteString(s)
@@ -0,0 +1,313 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expr is a filter expression over the fields of an entity, see ParseExpr
+type Expr interface {
+	Eval(e *Entity) bool
+}
+
+// ParseExpr parses a filter expression over the fields in FieldNames:
+//
+//	country = 中国 and (isp ~ 电信 or isp ~ 联通)
+//	not class = private && ip =~ '^10\.'
+//
+// Comparisons are field op value, the operators are
+// = or == for equality and != for inequality, both ignoring case,
+// ~ and !~ for containing a substring ignoring case and =~ for a regexp match.
+// Comparisons are combined with and, or, not and parentheses,
+// && || and ! may be used instead. Values with spaces or operators are quoted
+// with double quotes and Go escapes or single quotes without escapes.
+func ParseExpr(s string) (Expr, error) {
+	tokens, err := lexExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("表达式第 %d 个字符处多余的 %q", t.pos+1, t.text)
+	}
+	return expr, nil
+}
+
+// MatchLine reports whether an annotated entity of es matches expr
+func MatchLine(expr Expr, es Entities) bool {
+	for _, e := range es {
+		if e.Type != TypePlain && expr.Eval(e) {
+			return true
+		}
+	}
+	return false
+}
+
+type andExpr struct{ left, right Expr }
+
+func (x andExpr) Eval(e *Entity) bool { return x.left.Eval(e) && x.right.Eval(e) }
+
+type orExpr struct{ left, right Expr }
+
+func (x orExpr) Eval(e *Entity) bool { return x.left.Eval(e) || x.right.Eval(e) }
+
+type notExpr struct{ expr Expr }
+
+func (x notExpr) Eval(e *Entity) bool { return !x.expr.Eval(e) }
+
+type compareExpr struct {
+	field string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (x compareExpr) Eval(e *Entity) bool {
+	v := e.Field(x.field)
+	switch x.op {
+	case "=", "==":
+		return strings.EqualFold(v, x.value)
+	case "!=":
+		return !strings.EqualFold(v, x.value)
+	case "~":
+		return strings.Contains(strings.ToLower(v), strings.ToLower(x.value))
+	case "!~":
+		return !strings.Contains(strings.ToLower(v), strings.ToLower(x.value))
+	case "=~":
+		return x.re.MatchString(v)
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// exprOps are the comparison operators, longer ones first
+var exprOps = []string{"==", "!=", "!~", "=~", "=", "~"}
+
+func lexExpr(s string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case r == '(':
+			tokens = append(tokens, exprToken{tokLParen, "(", i})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, exprToken{tokRParen, ")", i})
+			i++
+			continue
+		case strings.HasPrefix(s[i:], "&&"):
+			tokens = append(tokens, exprToken{tokAnd, "&&", i})
+			i += 2
+			continue
+		case strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, exprToken{tokOr, "||", i})
+			i += 2
+			continue
+		case r == '"' || r == '\'':
+			text, n, err := lexString(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("表达式第 %d 个字符处的字符串无效: %v", i+1, err)
+			}
+			tokens = append(tokens, exprToken{tokString, text, i})
+			i += n
+			continue
+		}
+
+		if op := lexOp(s[i:]); op != "" {
+			tokens = append(tokens, exprToken{tokOp, op, i})
+			i += len(op)
+			continue
+		}
+		if r == '!' {
+			tokens = append(tokens, exprToken{tokNot, "!", i})
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if unicode.IsSpace(r) || strings.ContainsRune("()\"'=!~&|", r) {
+				break
+			}
+			i += size
+		}
+		word := s[start:i]
+		switch strings.ToLower(word) {
+		case "and":
+			tokens = append(tokens, exprToken{tokAnd, word, start})
+		case "or":
+			tokens = append(tokens, exprToken{tokOr, word, start})
+		case "not":
+			tokens = append(tokens, exprToken{tokNot, word, start})
+		default:
+			tokens = append(tokens, exprToken{tokWord, word, start})
+		}
+	}
+	return append(tokens, exprToken{tokEOF, "", len(s)}), nil
+}
+
+func lexOp(s string) string {
+	for _, op := range exprOps {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// lexString reads the quoted string at the start of s and returns its value and length
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote == '\'' {
+				return s[1:i], i + 1, nil
+			}
+			value, err := strconv.Unquote(s[:i+1])
+			return value, i + 1, err
+		}
+	}
+	return "", 0, errors.New("缺少结束引号")
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) and() (Expr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) not() (Expr, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		expr, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, fmt.Errorf("表达式第 %d 个字符处缺少右括号", t.pos+1)
+		}
+		return expr, nil
+	case tokWord:
+	case tokEOF:
+		return nil, errors.New("表达式不完整")
+	default:
+		return nil, fmt.Errorf("表达式第 %d 个字符处应为字段名，而不是 %q", t.pos+1, t.text)
+	}
+
+	field := strings.ToLower(t.text)
+	if !isFieldName(field) {
+		return nil, fmt.Errorf("未知的字段 %s，可选 %s", t.text, strings.Join(FieldNames, ", "))
+	}
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("表达式第 %d 个字符处缺少比较运算符", op.pos+1)
+	}
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, fmt.Errorf("表达式第 %d 个字符处缺少 %s 的比较值", value.pos+1, field)
+	}
+
+	x := compareExpr{field: field, op: op.text, value: value.text}
+	if x.op == "=~" {
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式 %s 无效: %v", value.text, err)
+		}
+		x.re = re
+	}
+	return x, nil
+}
@@ -0,0 +1,69 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+func TestParseExpr(t *testing.T) {
+	es := Entities{
+		{Type: TypeIPv4, Text: "54.239.28.85", Source: "geoip", Fields: iprange.Fields{Country: "美国", CountryCode: "US", City: "Seattle", ISP: "Amazon.com, Inc."}},
+		{Type: TypePlain, Text: " via "},
+		{Type: TypeIPv4, Text: "10.1.2.3", Source: "bogon", Class: "private"},
+	}
+
+	tests := []struct {
+		expr string
+		want []bool
+	}{
+		{`country = 美国`, []bool{true, false}},
+		{`country_code == us`, []bool{true, false}},
+		{`country != 美国`, []bool{false, true}},
+		{`isp ~ amazon`, []bool{true, false}},
+		{`isp !~ amazon`, []bool{false, true}},
+		{`ip =~ '^10\.'`, []bool{false, true}},
+		{`ip =~ "^10\\."`, []bool{false, true}},
+		{`isp = "Amazon.com, Inc."`, []bool{true, false}},
+		{`country=美国&&city=Seattle`, []bool{true, false}},
+		{`country = 美国 and city = Tokyo or class = private`, []bool{false, true}},
+		{`country = 美国 and (city = Tokyo or class = private)`, []bool{false, false}},
+		{`not class = private`, []bool{true, false}},
+		{`!(source = geoip || source = bogon)`, []bool{false, false}},
+		{`NOT not type = ipv4`, []bool{true, true}},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		for i, e := range []*Entity{es[0], es[2]} {
+			if got := expr.Eval(e); got != tt.want[i] {
+				t.Errorf("%q on %s = %v, want %v", tt.expr, e.Text, got, tt.want[i])
+			}
+		}
+		if got := MatchLine(expr, es); got != (tt.want[0] || tt.want[1]) {
+			t.Errorf("MatchLine(%q) = %v", tt.expr, got)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`country`,
+		`country =`,
+		`continent = 亚洲`,
+		`country = 美国 and`,
+		`(country = 美国`,
+		`country = 美国)`,
+		`ip =~ '('`,
+		`isp = "unterminated`,
+		`= 美国`,
+	} {
+		if _, err := ParseExpr(s); err == nil {
+			t.Errorf("ParseExpr(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package entity
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/abc1763613206/nabili/pkg/bogon"
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// Filter decides which entities of a line are looked up and annotated,
+// the skipped ones are left as plain text
+type Filter struct {
+	// Types are the entity types to annotate, all of them if empty
+	Types []EntityType
+	// SkipPrivate skips private, shared, loopback, link-local and unique-local addresses
+	SkipPrivate bool
+	// ExcludeCIDRs are the ranges whose addresses are not annotated
+	ExcludeCIDRs []netip.Prefix
+}
+
+// CmdFilter is the filter set by the command line flags, it is applied by ParseLine
+var CmdFilter Filter
+
+// typeFlags are the entity type names used in command line flags
+var typeFlags = map[string][]EntityType{
+	"ipv4":   {TypeIPv4},
+	"ipv6":   {TypeIPv6},
+	"ip":     {TypeIPv4, TypeIPv6},
+	"domain": {TypeDomain},
+}
+
+// ParseTypes parses entity type names: ipv4, ipv6, ip and domain
+func ParseTypes(names []string) ([]EntityType, error) {
+	var types []EntityType
+	for _, name := range names {
+		for _, name := range strings.Split(name, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			t, found := typeFlags[name]
+			if !found {
+				return nil, fmt.Errorf("未知的类型 %s，可选 ipv4, ipv6, ip, domain", name)
+			}
+			types = append(types, t...)
+		}
+	}
+	return types, nil
+}
+
+// ParseCIDRs parses CIDRs and single addresses, items may also be comma separated
+func ParseCIDRs(items []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range items {
+		for _, s := range strings.Split(item, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			if !strings.Contains(s, "/") {
+				ip, err := netip.ParseAddr(s)
+				if err != nil {
+					return nil, err
+				}
+				prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+				continue
+			}
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, p.Masked())
+		}
+	}
+	return prefixes, nil
+}
+
+// Skip reports whether e should be left as plain text
+func (f Filter) Skip(e *Entity) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return true
+		}
+	}
+	if e.Type != TypeIPv4 && e.Type != TypeIPv6 || !f.SkipPrivate && len(f.ExcludeCIDRs) == 0 {
+		return false
+	}
+
+	ip, err := netip.ParseAddr(e.Text)
+	if err != nil {
+		return false
+	}
+	if f.SkipPrivate && bogon.IsPrivate(ip) {
+		return true
+	}
+	ip = ip.WithZone("").Unmap()
+	for _, p := range f.ExcludeCIDRs {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Condition is a field=value test on the annotation of an entity, see Entity.Field
+type Condition struct {
+	Field string
+	Value string
+}
+
+// Where is a list of conditions that must all hold for a single entity of a line
+type Where []Condition
+
+// ParseWhere parses field=value conditions
+func ParseWhere(items []string) (Where, error) {
+	var w Where
+	for _, item := range items {
+		k := strings.IndexByte(item, '=')
+		if k <= 0 {
+			return nil, fmt.Errorf("条件 %s 格式错误，应为 field=value", item)
+		}
+		field := strings.ToLower(strings.TrimSpace(item[:k]))
+		if !isFieldName(field) {
+			return nil, fmt.Errorf("未知的字段 %s，可选 %s", field, strings.Join(FieldNames, ", "))
+		}
+		w = append(w, Condition{Field: field, Value: strings.TrimSpace(item[k+1:])})
+	}
+	return w, nil
+}
+
+// Match reports whether an annotated entity of es meets all conditions
+func (w Where) Match(es Entities) bool {
+	for _, e := range es {
+		if e.Type == TypePlain {
+			continue
+		}
+		matched := true
+		for _, c := range w {
+			if e.Field(c.Field) != c.Value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldNames are the names accepted by Entity.Field,
+// the location fields are normalized from the results of every database
+var FieldNames = append([]string{"type", "ip", "source", "class", "domain", "text"}, iprange.FieldNames...)
+
+func isFieldName(name string) bool {
+	for _, n := range FieldNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Field returns the field of the entity by name, see FieldNames
+func (e *Entity) Field(name string) string {
+	switch name {
+	case "type":
+		for n, t := range typeFlags {
+			if len(t) == 1 && t[0] == e.Type {
+				return n
+			}
+		}
+		return ""
+	case "ip":
+		return e.Text
+	case "source":
+		return e.Source
+	case "class":
+		return e.Class
+	case "domain":
+		return e.Domain
+	case "text":
+		return e.InfoText
+	}
+	return e.Fields.Get(name)
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+func TestFilterSkip(t *testing.T) {
+	types, err := ParseTypes([]string{"ip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixes, err := ParseCIDRs([]string{"192.0.2.0/24,2001:db8::/32", "203.0.113.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := Filter{Types: types, SkipPrivate: true, ExcludeCIDRs: prefixes}
+
+	tests := []struct {
+		typ  EntityType
+		text string
+		want bool
+	}{
+		{TypeIPv4, "8.8.8.8", false},
+		{TypeIPv4, "10.0.0.1", true},
+		{TypeIPv6, "fe80::1%eth0", true},
+		{TypeIPv4, "192.0.2.55", true},
+		{TypeIPv6, "::ffff:192.0.2.55", true},
+		{TypeIPv6, "2001:db8::1", true},
+		{TypeIPv4, "203.0.113.7", true},
+		{TypeIPv4, "203.0.113.8", false},
+		{TypeDomain, "www.example.com", true},
+	}
+	for _, tt := range tests {
+		if got := f.Skip(&Entity{Type: tt.typ, Text: tt.text}); got != tt.want {
+			t.Errorf("Skip(%s) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	if (Filter{}).Skip(&Entity{Type: TypeDomain, Text: "www.example.com"}) {
+		t.Error("the zero Filter skipped a domain")
+	}
+	if _, err := ParseTypes([]string{"ipv4,mac"}); err == nil {
+		t.Error("ParseTypes accepted mac")
+	}
+	if _, err := ParseCIDRs([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("ParseCIDRs accepted 10.0.0.0/33")
+	}
+}
+
+func TestWhere(t *testing.T) {
+	es := Entities{
+		{Type: TypePlain, Text: "from "},
+		{Type: TypeIPv4, Text: "1.2.3.4", Fields: iprange.Fields{Country: "中国", Region: "广东", ISP: "电信"}},
+		{Type: TypeIPv4, Text: "8.8.8.8", Fields: iprange.Fields{Country: "美国", ISP: "Google"}},
+	}
+
+	tests := []struct {
+		conditions []string
+		want       bool
+	}{
+		{[]string{"country=中国"}, true},
+		{[]string{"country=中国", "isp=电信"}, true},
+		{[]string{"country=中国", "isp=Google"}, false},
+		{[]string{"type=ipv4", "ip=8.8.8.8"}, true},
+		{[]string{"country=日本"}, false},
+	}
+	for _, tt := range tests {
+		w, err := ParseWhere(tt.conditions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Match(es); got != tt.want {
+			t.Errorf("%v.Match = %v, want %v", tt.conditions, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"country", "=中国", "continent=亚洲"} {
+		if _, err := ParseWhere([]string{bad}); err == nil {
+			t.Errorf("ParseWhere(%q) succeeded", bad)
+		}
+	}
+}
@@ -1,45 +1,16 @@
 package entity
 
 import (
-	"net/netip"
 	"sort"
 
 	"github.com/abc1763613206/nabili/internal/db"
 	"github.com/abc1763613206/nabili/pkg/dbif"
-	"github.com/abc1763613206/nabili/pkg/re"
+	"github.com/abc1763613206/nabili/pkg/psl"
 )
 
-// ParseLine parse a line into entities
+// ParseLine parse a line into entities, entities skipped by CmdFilter are not looked up
 func ParseLine(line string) Entities {
-	ip4sLoc := re.IPv4Re.FindAllStringIndex(line, -1)
-	ip6sLoc := re.IPv6Re.FindAllStringIndex(line, -1)
-	domainsLoc := re.DomainRe.FindAllStringIndex(line, -1)
-
-	tmp := make(Entities, 0, len(ip4sLoc)+len(ip6sLoc)+len(domainsLoc))
-	for _, e := range ip4sLoc {
-		tmp = append(tmp, &Entity{
-			Loc:  *(*[2]int)(e),
-			Type: TypeIPv4,
-			Text: line[e[0]:e[1]],
-		})
-	}
-	for _, e := range ip6sLoc {
-		text := line[e[0]:e[1]]
-		if ip, _ := netip.ParseAddr(text); !ip.Is4In6() {
-			tmp = append(tmp, &Entity{
-				Loc:  *(*[2]int)(e),
-				Type: TypeIPv6,
-				Text: text,
-			})
-		}
-	}
-	for _, e := range domainsLoc {
-		tmp = append(tmp, &Entity{
-			Loc:  *(*[2]int)(e),
-			Type: TypeDomain,
-			Text: line[e[0]:e[1]],
-		})
-	}
+	tmp := Tokenize(line)
 
 	sort.Sort(tmp)
 	var es Entities
@@ -55,11 +26,27 @@ func ParseLine(line string) Entities {
 					Text: line[idx:start],
 				})
 			}
-			res := db.Find(dbif.QueryType(e.Type), e.Text)
+			if CmdFilter.Skip(e) {
+				e.Type = TypePlain
+				idx = e.Loc[1]
+				es = append(es, e)
+				continue
+			}
+			query := e.Text
+			if e.Type == TypeDomain {
+				// databases are keyed by the xn-- form of internationalized domains
+				if ascii, err := psl.ToASCII(query); err == nil {
+					query = ascii
+				}
+			}
+			res := db.Find(dbif.QueryType(e.Type), query)
 			if res != nil {
 				e.InfoText = res.String()
 				e.Info = res.Result
 				e.Source = res.Source
+				e.Class = res.Class
+				e.Transition = res.Transition
+				e.Fields = res.Fields()
 			} else {
 				e.Type = TypePlain
 			}
@@ -0,0 +1,202 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+// AnnotateMode is where the annotations are placed in the output line
+type AnnotateMode string
+
+const (
+	// AnnotateInline inserts [info] right after the entity
+	AnnotateInline AnnotateMode = "inline"
+	// AnnotateReplace replaces the entity with its info
+	AnnotateReplace AnnotateMode = "replace"
+	// AnnotateEOL leaves the line as is and appends the annotations at its end
+	AnnotateEOL AnnotateMode = "eol"
+	// AnnotateColumn appends [info] to the whitespace separated column of the entity
+	// and pads it to the widest annotation seen in that column, so tables stay aligned
+	AnnotateColumn AnnotateMode = "column"
+)
+
+// AnnotateModes are the modes accepted by ParseAnnotateMode
+var AnnotateModes = []AnnotateMode{AnnotateInline, AnnotateReplace, AnnotateEOL, AnnotateColumn}
+
+// ParseAnnotateMode parses the name of an annotate mode, the empty name is inline
+func ParseAnnotateMode(s string) (AnnotateMode, error) {
+	if s == "" {
+		return AnnotateInline, nil
+	}
+	for _, mode := range AnnotateModes {
+		if string(mode) == strings.ToLower(s) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("未知的标注方式 %s，可选 inline, replace, eol, column", s)
+}
+
+// Renderer renders annotated lines in a mode, it keeps the column widths between
+// the lines of a stream for AnnotateColumn
+type Renderer struct {
+	Mode AnnotateMode
+
+	widths map[int]int
+}
+
+// Render returns the colored line, the line ending of the last entity is kept
+func (r *Renderer) Render(es Entities) string {
+	switch r.Mode {
+	case AnnotateReplace:
+		return es.replaceString()
+	case AnnotateEOL:
+		return es.eolString()
+	case AnnotateColumn:
+		return r.columnString(es)
+	default:
+		return es.ColorString()
+	}
+}
+
+// Render returns the line annotated in mode, see Renderer for streams in AnnotateColumn
+func (es Entities) Render(mode AnnotateMode) string {
+	r := Renderer{Mode: mode}
+	return r.Render(es)
+}
+
+func (e *Entity) annotated() bool {
+	return e.Type != TypePlain && len(e.InfoText) > 0
+}
+
+func (e *Entity) colorToken() string {
+	s := e.token()
+	switch e.Type {
+	case TypeIPv4:
+		s = color.GreenString(s)
+	case TypeIPv6:
+		s = color.BlueString(s)
+	case TypeDomain:
+		s = color.YellowString(s)
+	}
+	return s
+}
+
+func (es Entities) replaceString() string {
+	var line strings.Builder
+	for _, e := range es {
+		if !e.annotated() {
+			line.WriteString(e.token())
+			continue
+		}
+		// keep the port, prefix length or URL around the host
+		token := e.token()
+		if k := strings.Index(token, e.Text); k >= 0 {
+			line.WriteString(token[:k] + color.RedString(e.InfoText) + token[k+len(e.Text):])
+		} else {
+			line.WriteString(color.RedString(e.InfoText))
+		}
+	}
+	return line.String()
+}
+
+func (es Entities) eolString() string {
+	var line strings.Builder
+	var notes []string
+	seen := make(map[string]bool)
+	for _, e := range es {
+		if e.Type == TypePlain {
+			// skipped entities keep their port, prefix length or URL
+			line.WriteString(e.token())
+			continue
+		}
+		line.WriteString(e.colorToken())
+		if e.annotated() && !seen[e.Text] {
+			seen[e.Text] = true
+			notes = append(notes, e.Text+" ["+color.RedString(e.InfoText)+"]")
+		}
+	}
+	if len(notes) == 0 {
+		return line.String()
+	}
+	s := line.String()
+	body := strings.TrimRight(s, "\r\n")
+	return body + "  # " + strings.Join(notes, ", ") + s[len(body):]
+}
+
+func (r *Renderer) columnString(es Entities) string {
+	if r.widths == nil {
+		r.widths = make(map[int]int)
+	}
+
+	// annotations of every whitespace separated column
+	notes := make(map[int][]string)
+	field, inField := -1, false
+	for _, e := range es {
+		if e.Type != TypePlain {
+			if !inField {
+				field, inField = field+1, true
+			}
+			if e.annotated() {
+				notes[field] = append(notes[field], e.InfoText)
+			}
+			continue
+		}
+		for _, c := range e.token() {
+			if unicode.IsSpace(c) {
+				inField = false
+			} else if !inField {
+				field, inField = field+1, true
+			}
+		}
+	}
+	for f, texts := range notes {
+		if w := common.DisplayWidth(" [" + strings.Join(texts, "] [") + "]"); w > r.widths[f] {
+			r.widths[f] = w
+		}
+	}
+
+	var line strings.Builder
+	end := func(f int) {
+		w := r.widths[f]
+		if w == 0 {
+			return
+		}
+		if texts := notes[f]; len(texts) > 0 {
+			for _, text := range texts {
+				line.WriteString(" [" + color.RedString(text) + "]")
+			}
+			w -= common.DisplayWidth(" [" + strings.Join(texts, "] [") + "]")
+		}
+		line.WriteString(strings.Repeat(" ", w))
+	}
+	field, inField = -1, false
+	for _, e := range es {
+		if e.Type != TypePlain {
+			if !inField {
+				field, inField = field+1, true
+			}
+			line.WriteString(e.colorToken())
+			continue
+		}
+		for _, c := range e.token() {
+			if unicode.IsSpace(c) {
+				if inField {
+					end(field)
+					inField = false
+				}
+			} else if !inField {
+				field, inField = field+1, true
+			}
+			line.WriteRune(c)
+		}
+	}
+	if inField {
+		end(field)
+	}
+	return line.String()
+}
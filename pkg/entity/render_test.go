@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func row(prefix, ip, port, info, suffix string) Entities {
+	token := ip + port
+	return Entities{
+		{Type: TypePlain, Text: prefix},
+		{Type: TypeIPv4, Text: ip, Token: token, InfoText: info},
+		{Type: TypePlain, Text: suffix},
+	}
+}
+
+func TestRender(t *testing.T) {
+	color.NoColor = true
+	es := row("tcp ", "1.2.3.4", ":443", "中国 电信", "  ESTAB\r\n")
+
+	tests := []struct {
+		mode AnnotateMode
+		want string
+	}{
+		{AnnotateInline, "tcp 1.2.3.4:443 [中国 电信]   ESTAB\r\n"},
+		{AnnotateReplace, "tcp 中国 电信:443  ESTAB\r\n"},
+		{AnnotateEOL, "tcp 1.2.3.4:443  ESTAB  # 1.2.3.4 [中国 电信]\r\n"},
+		{AnnotateColumn, "tcp 1.2.3.4:443 [中国 电信]  ESTAB\r\n"},
+	}
+	for _, tt := range tests {
+		if got := es.Render(tt.mode); got != tt.want {
+			t.Errorf("Render(%s) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+
+	if _, err := ParseAnnotateMode("side"); err == nil {
+		t.Error("ParseAnnotateMode accepted side")
+	}
+}
+
+func TestRenderColumn(t *testing.T) {
+	color.NoColor = true
+	r := &Renderer{Mode: AnnotateColumn}
+	lines := []Entities{
+		row("tcp  ", "1.2.3.4", ":80", "美国", "      ESTAB\n"),
+		row("tcp  ", "10.20.30.40", ":80", "局域网 IP", "  ESTAB\n"),
+		{{Type: TypePlain, Text: "tcp  *:*             LISTEN\n"}},
+	}
+	want := []string{
+		"tcp  1.2.3.4:80 [美国]      ESTAB\n",
+		"tcp  10.20.30.40:80 [局域网 IP]  ESTAB\n",
+		// the column has no annotation but is padded to stay aligned
+		"tcp  *:*" + strings.Repeat(" ", 12) + "             LISTEN\n",
+	}
+	for i, es := range lines {
+		if got := r.Render(es); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+	// later lines are padded to the widest annotation of the column
+	if got, want := r.Render(lines[0]), "tcp  1.2.3.4:80 [美国]"+strings.Repeat(" ", 5)+"      ESTAB\n"; got != want {
+		t.Errorf("padded line = %q, want %q", got, want)
+	}
+}
+
+func TestRenderSkipped(t *testing.T) {
+	color.NoColor = true
+	// 1.2.3.4:443 was skipped by --only ipv6 or not found in the database
+	es := Entities{
+		{Type: TypePlain, Text: "connect "},
+		{Type: TypePlain, Text: "1.2.3.4", Token: "1.2.3.4:443"},
+		{Type: TypePlain, Text: " ok\n"},
+	}
+	for _, mode := range AnnotateModes {
+		if got := es.Render(mode); got != "connect 1.2.3.4:443 ok\n" {
+			t.Errorf("Render(%s) = %q, want the line as is", mode, got)
+		}
+	}
+
+	es = append(row("", "5.6.7.8", ":22", "美国", " "), es...)
+	if got, want := es.Render(AnnotateEOL), "5.6.7.8:22 connect 1.2.3.4:443 ok  # 5.6.7.8 [美国]\n"; got != want {
+		t.Errorf("Render(eol) = %q, want %q", got, want)
+	}
+	if got, want := es.Render(AnnotateColumn), "5.6.7.8:22 [美国] connect 1.2.3.4:443 ok\n"; got != want {
+		t.Errorf("Render(column) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package entity
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// partialDelay is how long an unfinished line waits before it is shown without annotations
+const partialDelay = 100 * time.Millisecond
+
+// Stream annotates the lines read from src and writes them to dst as they come,
+// read errors accepted by isEOF end the stream like io.EOF.
+//
+// If live is set, a line that is not finished after a short while, like a
+// traceroute hop waiting for its probes, is written as is and rewritten with
+// its annotations after a carriage return once it is finished.
+func (r *Renderer) Stream(dst io.Writer, src io.Reader, live bool, isEOF func(error) bool) error {
+	type chunk struct {
+		data []byte
+		err  error
+	}
+	chunks := make(chan chunk)
+	// done stops the reader if the stream ends early on a write error
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		send := func(c chunk) bool {
+			select {
+			case chunks <- c:
+				return true
+			case <-done:
+				return false
+			}
+		}
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 && !send(chunk{data: append([]byte(nil), buf[:n]...)}) {
+				return
+			}
+			if err != nil {
+				send(chunk{err: err})
+				return
+			}
+		}
+	}()
+
+	var pending []byte
+	shown := 0
+	write := func(line []byte) error {
+		out := r.Render(ParseLine(string(line)))
+		if shown > 0 {
+			out = "\r" + out
+		}
+		shown = 0
+		_, err := io.WriteString(dst, out)
+		return err
+	}
+
+	timer := time.NewTimer(partialDelay)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case c := <-chunks:
+			if c.err != nil {
+				if len(pending) > 0 {
+					if err := write(pending); err != nil {
+						return err
+					}
+				}
+				if c.err == io.EOF || isEOF != nil && isEOF(c.err) {
+					return nil
+				}
+				return c.err
+			}
+			pending = append(pending, c.data...)
+			for {
+				end := lineEnd(pending)
+				if end < 0 {
+					break
+				}
+				if err := write(pending[:end]); err != nil {
+					return err
+				}
+				pending = pending[end:]
+			}
+			if live && len(pending) > shown {
+				// drop a tick of the previous partial line, it would flush this one early
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(partialDelay)
+			}
+		case <-timer.C:
+			if len(pending) > shown {
+				if _, err := dst.Write(pending[shown:]); err != nil {
+					return err
+				}
+				shown = len(pending)
+			}
+		}
+	}
+}
+
+// lineEnd returns the length of the first line in data with its \n, \r\n or \r ending,
+// or -1 if the line is not finished yet. A trailing \r may still be followed by \n.
+func lineEnd(data []byte) int {
+	i := bytes.IndexAny(data, "\r\n")
+	switch {
+	case i < 0:
+		return -1
+	case data[i] == '\n':
+		return i + 1
+	case i+1 == len(data):
+		return -1
+	case data[i+1] == '\n':
+		return i + 2
+	}
+	return i + 1
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineEnd(t *testing.T) {
+	for s, want := range map[string]int{
+		"abc":       -1,
+		"abc\n":     4,
+		"abc\r\nd":  5,
+		"abc\r":     -1,
+		"abc\rdef":  4,
+		"\n\n":      1,
+		"abc\r\r\n": 4,
+	} {
+		if got := lineEnd([]byte(s)); got != want {
+			t.Errorf("lineEnd(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	pr, pw := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error)
+	go func() {
+		r := &Renderer{Mode: AnnotateInline}
+		done <- r.Stream(&out, pr, true, nil)
+	}()
+
+	_, _ = pw.Write([]byte("first\r\nhop 1 "))
+	time.Sleep(3 * partialDelay)
+	_, _ = pw.Write([]byte("done\nlast"))
+	_ = pw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// the unfinished hop is shown first and rewritten once finished
+	want := "first\r\nhop 1 \rhop 1 done\nlast"
+	if out.String() != want {
+		t.Errorf("Stream = %q, want %q", out.String(), want)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+// endless returns lines until the test ends
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	return copy(p, strings.Repeat("line\n", len(p)/5)), nil
+}
+
+func TestStreamWriteError(t *testing.T) {
+	before := runtime.NumGoroutine()
+	r := &Renderer{Mode: AnnotateInline}
+	if err := r.Stream(failWriter{}, endless{}, true, nil); err == nil {
+		t.Fatal("Stream ignored the write error")
+	}
+
+	// the reader goroutine exits instead of blocking on its next chunk
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left running after Stream returned", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,416 @@
+package entity
+
+import (
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/abc1763613206/nabili/internal/db"
+	"github.com/abc1763613206/nabili/pkg/psl"
+)
+
+// Tokenize finds the IPv4, IPv6 and domain entities of a line in a single pass,
+// the entities are sorted by Loc and are not annotated.
+//
+// A candidate is a run of letters, digits and . : % - _ characters,
+// anything else is a word boundary. IPs glued to letters or extra octets,
+// like a1.2.3.4 or 1.2.3.4.5, are not entities.
+// Domains must end with a public suffix, their registrable domain is set in Domain.
+// Terminal escape sequences are word boundaries.
+//
+// Loc covers the whole token while Text is the host to look up:
+// 1.2.3.4:443 and [2001:db8::1]:8080 set Port, 10.0.0.0/8 sets Prefix
+// and the host of https://host.example.com/path is found with URL set.
+func Tokenize(line string) Entities {
+	var es Entities
+	for i := 0; i < len(line); {
+		if line[i] == '[' {
+			if e, ok := scanBracket(line, i); ok {
+				es = append(es, e)
+				i = e.Loc[1]
+				continue
+			}
+		}
+		if line[i] == '\x1b' {
+			i = skipEscape(line, i)
+			continue
+		}
+		size := tokenCharLen(line, i)
+		if size == 0 {
+			i++
+			continue
+		}
+		j := i + size
+		for j < len(line) {
+			if size = tokenCharLen(line, j); size == 0 {
+				break
+			}
+			j += size
+		}
+
+		if line[j-1] == ':' && strings.HasPrefix(line[j:], "//") && isScheme(line[i:j-1]) {
+			if e, ok := scanURL(line, i); ok {
+				es = append(es, e)
+				i = e.Loc[1]
+				continue
+			}
+		}
+
+		n := len(es)
+		es = scanRun(es, line, i, j)
+		if len(es) > n {
+			if end, ok := scanPrefix(line, es[len(es)-1], j); ok {
+				j = end
+			}
+		}
+		i = j
+	}
+
+	for _, e := range es {
+		if e.Type == TypeDomain {
+			if ascii, err := psl.ToASCII(e.Text); err == nil {
+				e.Domain = db.PublicSuffixList().Registrable(ascii)
+			}
+		}
+	}
+	return es
+}
+
+func isTokenChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c == '.' || c == ':' || c == '%' || c == '-' || c == '_'
+}
+
+// skipEscape returns the end of the terminal escape sequence at line[i],
+// so that colored output like \x1b[32m1.2.3.4\x1b[0m is tokenized without the codes
+func skipEscape(line string, i int) int {
+	i++
+	if i >= len(line) || line[i] != '[' && line[i] != ']' {
+		return i
+	}
+	if line[i] == ']' {
+		// OSC sequences end with BEL or ESC \
+		for i++; i < len(line); i++ {
+			if line[i] == '\a' {
+				return i + 1
+			}
+			if line[i] == '\x1b' {
+				return i
+			}
+		}
+		return i
+	}
+	// CSI sequences end with a byte in 0x40-0x7e
+	for i++; i < len(line); i++ {
+		if line[i] >= 0x40 && line[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// tokenCharLen returns the size of the token character at line[i], or 0.
+// Non-ASCII letters and digits are token characters for internationalized domains.
+func tokenCharLen(line string, i int) int {
+	if c := line[i]; c < utf8.RuneSelf {
+		if isTokenChar(c) {
+			return 1
+		}
+		return 0
+	}
+	r, size := utf8.DecodeRuneInString(line[i:])
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+		return size
+	}
+	return 0
+}
+
+// scanRun appends the entities of line[start:end], the run is tried as a whole first
+// and then split between ASCII and other scripts, like 来自1.2.3.4的请求,
+// and at - : and % since those also separate ranges, ports and words
+func scanRun(es Entities, line string, start, end int) Entities {
+	run := line[start:end]
+	if strings.IndexByte(run, '.') < 0 && strings.IndexByte(run, ':') < 0 {
+		return es
+	}
+
+	if typ, s, e, ok := classify(run); ok {
+		return append(es, &Entity{
+			Loc:  [2]int{start + s, start + e},
+			Type: typ,
+			Text: run[s:e],
+		})
+	}
+
+	// host:port, IPv6 with ports must be bracketed
+	if k := strings.IndexByte(run, ':'); k > 0 && k == strings.LastIndexByte(run, ':') {
+		pe := len(run)
+		for pe > k+1 && run[pe-1] == '.' {
+			pe--
+		}
+		if port, ok := parsePort(run[k+1 : pe]); ok {
+			if typ, s, e, ok := classify(run[:k]); ok && e == k && typ != TypeIPv6 {
+				return append(es, &Entity{
+					Loc:   [2]int{start + s, start + pe},
+					Type:  typ,
+					Text:  run[s:e],
+					Token: run[s:pe],
+					Port:  port,
+				})
+			}
+		}
+	}
+
+	if split := scriptBoundaries(run); len(split) > 0 {
+		pos := start
+		for _, k := range split {
+			es = scanRun(es, line, pos, start+k)
+			pos = start + k
+		}
+		return scanRun(es, line, pos, end)
+	}
+
+	for _, sep := range []byte{'-', ':', '%'} {
+		if strings.IndexByte(run, sep) < 0 {
+			continue
+		}
+		pos := start
+		for k := start; k <= end; k++ {
+			if k == end || line[k] == sep {
+				if k > pos {
+					es = scanRun(es, line, pos, k)
+				}
+				pos = k + 1
+			}
+		}
+		return es
+	}
+	return es
+}
+
+// classify reports the entity type of a run and its bounds without surrounding punctuation
+func classify(run string) (typ EntityType, start, end int, ok bool) {
+	// IPv6 may start with :: and end with ::, but not with a single colon
+	if strings.IndexByte(run, ':') >= 0 {
+		s, e := 0, len(run)
+		for e > 0 && (run[e-1] == '.' || run[e-1] == '-' || run[e-1] == '%') {
+			e--
+		}
+		if e > 1 && run[e-1] == ':' && run[e-2] != ':' {
+			e--
+		}
+		if s < e && run[s] == ':' && (e-s < 2 || run[s+1] != ':') {
+			s++
+		}
+		if s < e && isIPv6(run[s:e]) {
+			return TypeIPv6, s, e, true
+		}
+	}
+
+	s, e := 0, len(run)
+	for s < e && (run[s] == '.' || run[s] == '-' || run[s] == '_') {
+		s++
+	}
+	for e > s && (run[e-1] == '.' || run[e-1] == '-' || run[e-1] == '_') {
+		e--
+	}
+	switch {
+	case s == e:
+	case isIPv4(run[s:e]):
+		return TypeIPv4, s, e, true
+	case isDomain(run[s:e]):
+		return TypeDomain, s, e, true
+	}
+	return 0, 0, 0, false
+}
+
+func isIPv4(s string) bool {
+	if strings.Count(s, ".") != 3 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '.' && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	ip, err := netip.ParseAddr(s)
+	return err == nil && ip.Is4()
+}
+
+func isIPv6(s string) bool {
+	if strings.Count(s, ":") < 2 {
+		return false
+	}
+	ip, err := netip.ParseAddr(s)
+	return err == nil && ip.Is6()
+}
+
+// scriptBoundaries returns the offsets in run where non-ASCII text meets ASCII text other than dots
+func scriptBoundaries(run string) []int {
+	var split []int
+	for k := 1; k < len(run); k++ {
+		prev, cur := run[k-1], run[k]
+		if cur >= utf8.RuneSelf && !utf8.RuneStart(cur) {
+			continue
+		}
+		if prev >= utf8.RuneSelf && cur < utf8.RuneSelf && cur != '.' ||
+			cur >= utf8.RuneSelf && prev < utf8.RuneSelf && prev != '.' {
+			split = append(split, k)
+		}
+	}
+	return split
+}
+
+// isDomain reports whether s has at least two labels of letters, digits and inner hyphens
+// and ends with a public suffix, Unicode and xn-- labels must be valid IDNA
+func isDomain(s string) bool {
+	labels := 0
+	last := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] != '.' {
+			c := s[i]
+			if c == ':' || c == '%' || c == '_' {
+				return false
+			}
+			continue
+		}
+		label := s[last:i]
+		if len(label) == 0 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		labels++
+		last = i + 1
+	}
+	if labels < 2 {
+		return false
+	}
+
+	ascii, err := psl.ToASCII(s)
+	if err != nil || len(ascii) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if len(label) > 63 {
+			return false
+		}
+	}
+	if _, err := psl.ToUnicode(ascii); err != nil {
+		return false
+	}
+	_, known := db.PublicSuffixList().PublicSuffix(ascii)
+	return known
+}
+
+// scanBracket reads a bracketed IPv6 address at line[i] with an optional port
+func scanBracket(line string, i int) (*Entity, bool) {
+	k := strings.IndexByte(line[i:], ']')
+	if k < 3 || k > 64 || !isIPv6(line[i+1:i+k]) {
+		return nil, false
+	}
+	e := &Entity{Type: TypeIPv6, Text: line[i+1 : i+k]}
+	end := i + k + 1
+	if end < len(line) && line[end] == ':' {
+		pe := end + 1
+		for pe < len(line) && pe-end <= 5 && line[pe] >= '0' && line[pe] <= '9' {
+			pe++
+		}
+		if port, ok := parsePort(line[end+1 : pe]); ok && (pe == len(line) || !isTokenChar(line[pe]) || line[pe] == '.') {
+			e.Port, end = port, pe
+		}
+	}
+	e.Loc = [2]int{i, end}
+	e.Token = line[i:end]
+	return e, true
+}
+
+// scanPrefix extends an IP entity ending at line[end] with a /bits prefix length
+func scanPrefix(line string, e *Entity, end int) (int, bool) {
+	if e.Loc[1] != end || end >= len(line) || line[end] != '/' || e.Type == TypeDomain || e.Port != 0 {
+		return 0, false
+	}
+	pe := end + 1
+	for pe < len(line) && pe-end <= 3 && line[pe] >= '0' && line[pe] <= '9' {
+		pe++
+	}
+	if pe == end+1 || (pe < len(line) && (isTokenChar(line[pe]) && line[pe] != '.' || line[pe] == '/')) {
+		return 0, false
+	}
+	p, err := netip.ParsePrefix(e.Text + line[end:pe])
+	if err != nil {
+		return 0, false
+	}
+	bits := p.Bits()
+	e.Prefix = &bits
+	e.Loc[1] = pe
+	e.Token = line[e.Loc[0]:pe]
+	return pe, true
+}
+
+func isScheme(s string) bool {
+	if s == "" || !(s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// scanURL reads the URL starting at line[i], the entity is its host
+func scanURL(line string, i int) (*Entity, bool) {
+	end := i
+	for end < len(line) && !isURLStop(line[end]) {
+		end++
+	}
+	for end > i && strings.IndexByte(".,;:!?)]}", line[end-1]) >= 0 {
+		end--
+	}
+	u, err := url.Parse(line[i:end])
+	if err != nil || u.Host == "" {
+		return nil, false
+	}
+
+	host := u.Hostname()
+	e := &Entity{Loc: [2]int{i, end}, Text: host, Token: line[i:end], URL: line[i:end]}
+	switch {
+	case isIPv4(host):
+		e.Type = TypeIPv4
+	case isIPv6(host):
+		e.Type = TypeIPv6
+	case isDomain(host):
+		e.Type = TypeDomain
+	default:
+		return nil, false
+	}
+	if p := u.Port(); p != "" {
+		port, ok := parsePort(p)
+		if !ok {
+			return nil, false
+		}
+		e.Port = port
+	}
+	return e, true
+}
+
+func isURLStop(c byte) bool {
+	return c <= ' ' || c == '"' || c == '\'' || c == '<' || c == '>' || c == '`'
+}
+
+func parsePort(s string) (uint16, bool) {
+	if s == "" || len(s) > 5 {
+		return 0, false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+	}
+	port, err := strconv.ParseUint(s, 10, 16)
+	return uint16(port), err == nil && port > 0
+}
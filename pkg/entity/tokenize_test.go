@@ -0,0 +1,164 @@
+package entity
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/abc1763613206/nabili/internal/constant"
+	"github.com/abc1763613206/nabili/pkg/re"
+)
+
+var update = flag.Bool("update", false, "update testdata/corpus.golden")
+
+// the constant package changes the working directory to the data dir
+var golden = filepath.Join(constant.WorkDirPath, "testdata/corpus.golden")
+
+var typeNames = map[EntityType]string{
+	TypeIPv4:   "ipv4",
+	TypeIPv6:   "ipv6",
+	TypeDomain: "domain",
+}
+
+func formatTokens(line string, es Entities) string {
+	var s strings.Builder
+	for _, e := range es {
+		if line[e.Loc[0]:e.Loc[1]] != e.token() {
+			panic(fmt.Sprintf("Loc %v of %q does not match the token %q", e.Loc, line, e.token()))
+		}
+		fmt.Fprintf(&s, "\t%s %d-%d %s", typeNames[e.Type], e.Loc[0], e.Loc[1], e.Text)
+		if e.Port != 0 {
+			fmt.Fprintf(&s, " port=%d", e.Port)
+		}
+		if e.Prefix != nil {
+			fmt.Fprintf(&s, " prefix=%d", *e.Prefix)
+		}
+		if e.URL != "" {
+			fmt.Fprintf(&s, " url=%s", e.URL)
+		}
+		if e.Domain != "" {
+			fmt.Fprintf(&s, " domain=%s", e.Domain)
+		}
+		s.WriteString("\n")
+	}
+	return s.String()
+}
+
+func readCorpus(tb testing.TB) []string {
+	tb.Helper()
+	data, err := os.ReadFile(filepath.Join(constant.WorkDirPath, "testdata/corpus.txt"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+// TestTokenizeCorpus compares the tokens of every corpus line with testdata/corpus.golden,
+// run go test -update to regenerate it after reviewing the changes
+func TestTokenizeCorpus(t *testing.T) {
+	var got strings.Builder
+	for _, line := range readCorpus(t) {
+		got.WriteString(line + "\n" + formatTokens(line, Tokenize(line)))
+	}
+
+	if *update {
+		if err := os.WriteFile(golden, []byte(got.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		gotLines := bufio.NewScanner(strings.NewReader(got.String()))
+		wantLines := bufio.NewScanner(strings.NewReader(string(want)))
+		for n := 1; ; n++ {
+			g, w := gotLines.Scan(), wantLines.Scan()
+			if !g && !w {
+				break
+			}
+			if gotLines.Text() != wantLines.Text() {
+				t.Fatalf("corpus.golden line %d:\n got: %q\nwant: %q", n, gotLines.Text(), wantLines.Text())
+			}
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"1.2.3.4.5", nil},
+		{"a1.2.3.4 1.2.3.4b", nil},
+		{"(1.2.3.4)", []string{"1.2.3.4"}},
+		{"fe80::1%eth0", []string{"fe80::1%eth0"}},
+		{"fe80::1%", []string{"fe80::1"}},
+		{"::ffff:1.2.3.4", []string{"::ffff:1.2.3.4"}},
+		{"1.2.3.4:80", []string{"1.2.3.4"}},
+		{"host a.b.com.", []string{"a.b.com"}},
+		{"2001:db8::1: refused", []string{"2001:db8::1"}},
+		{"::1.", []string{"::1"}},
+		{"\x1b[01;32m1.2.3.4\x1b[0m:\x1b[1m80\x1b[m", []string{"1.2.3.4"}},
+		{"\x1b]8;;http://example.com\x1b\\link\x1b]8;;\a 10.0.0.1", []string{"10.0.0.1"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, e := range Tokenize(tt.line) {
+			got = append(got, e.Text)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// regexTokens is the former ParseLine tokenizer, kept for the benchmarks
+func regexTokens(line string) Entities {
+	ip4sLoc := re.IPv4Re.FindAllStringIndex(line, -1)
+	ip6sLoc := re.IPv6Re.FindAllStringIndex(line, -1)
+	domainsLoc := re.DomainRe.FindAllStringIndex(line, -1)
+
+	tmp := make(Entities, 0, len(ip4sLoc)+len(ip6sLoc)+len(domainsLoc))
+	for _, e := range ip4sLoc {
+		tmp = append(tmp, &Entity{Loc: *(*[2]int)(e), Type: TypeIPv4, Text: line[e[0]:e[1]]})
+	}
+	for _, e := range ip6sLoc {
+		text := line[e[0]:e[1]]
+		if ip, _ := netip.ParseAddr(text); !ip.Is4In6() {
+			tmp = append(tmp, &Entity{Loc: *(*[2]int)(e), Type: TypeIPv6, Text: text})
+		}
+	}
+	for _, e := range domainsLoc {
+		tmp = append(tmp, &Entity{Loc: *(*[2]int)(e), Type: TypeDomain, Text: line[e[0]:e[1]]})
+	}
+	sort.Sort(tmp)
+	return tmp
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	lines := readCorpus(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			Tokenize(line)
+		}
+	}
+}
+
+func BenchmarkRegexTokens(b *testing.B) {
+	lines := readCorpus(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			regexTokens(line)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package geoip
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ASN is the autonomous system announcing an address
+type ASN struct {
+	Number       uint   `json:"asn"`
+	Organization string `json:"as_org,omitempty"`
+}
+
+func (a ASN) String() string {
+	if a.Organization == "" {
+		return fmt.Sprintf("AS%d", a.Number)
+	}
+	return fmt.Sprintf("AS%d %s", a.Number, a.Organization)
+}
+
+// ASNReader looks up GeoLite2-ASN and DB-IP ASN lite databases
+type ASNReader struct {
+	reader *maxminddb.Reader
+}
+
+func NewASNReader(filePath string) (*ASNReader, error) {
+	reader, err := maxminddb.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &ASNReader{reader: reader}, nil
+}
+
+// Lookup returns the autonomous system of ip, found is false if it is not announced
+func (r *ASNReader) Lookup(ip netip.Addr) (asn ASN, found bool, err error) {
+	var record struct {
+		Number       uint   `maxminddb:"autonomous_system_number"`
+		Organization string `maxminddb:"autonomous_system_organization"`
+	}
+	_, ok, err := r.reader.LookupNetwork(ip.AsSlice(), &record)
+	if err != nil || !ok || record.Number == 0 {
+		return ASN{}, false, err
+	}
+	return ASN{Number: record.Number, Organization: record.Organization}, true, nil
+}
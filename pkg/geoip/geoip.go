@@ -5,15 +5,20 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/netip"
 	"os"
 
 	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
 	"github.com/spf13/viper"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
 )
 
 // GeoIP2
 type GeoIP struct {
-	db *geoip2.Reader
+	db       *geoip2.Reader
+	filePath string
 }
 
 // new geoip from database file
@@ -21,14 +26,14 @@ func NewGeoIP(filePath string) (*GeoIP, error) {
 	// 判断文件是否存在
 	_, err := os.Stat(filePath)
 	if err != nil && os.IsNotExist(err) {
-		log.Println("文件不存在，请自行下载 Geoip2 City库，并保存在", filePath)
+		log.Println("文件不存在，请使用 nabili update --db geoip,dbip 下载，或自行下载 Geoip2 City库，并保存在", filePath)
 		return nil, err
 	} else {
 		db, err := geoip2.Open(filePath)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		return &GeoIP{db: db}, nil
+		return &GeoIP{db: db, filePath: filePath}, nil
 	}
 }
 
@@ -42,10 +47,7 @@ func (g GeoIP) Find(query string, params ...string) (result fmt.Stringer, err er
 		return
 	}
 
-	lang := viper.GetString("selected.lang")
-	if lang == "" {
-		lang = "zh-CN"
-	}
+	lang := selectedLang()
 
 	result = Result{
 		Country:     getMapLang(record.Country.Names, lang),
@@ -59,6 +61,86 @@ func (db GeoIP) Name() string {
 	return "geoip"
 }
 
+// rangeRecord covers City, Country, ASN and ISP databases
+type rangeRecord struct {
+	Country struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ISP                          string `maxminddb:"isp"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Ranges walks every network in the search tree, IPv4 aliases in IPv6 databases are skipped
+func (g GeoIP) Ranges(fn func(iprange.Range) error) error {
+	reader, err := maxminddb.Open(g.filePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	lang := selectedLang()
+	stopped := false
+	var pending iprange.Range
+	emit := func() error {
+		if !pending.Start.IsValid() {
+			return nil
+		}
+		err := fn(pending)
+		pending = iprange.Range{}
+		if err == iprange.ErrStop {
+			stopped = true
+			return nil
+		}
+		return err
+	}
+
+	networks := reader.Networks(maxminddb.SkipAliasedNetworks)
+	for networks.Next() {
+		var record rangeRecord
+		network, err := networks.Network(&record)
+		if err != nil {
+			return err
+		}
+		ip, _ := netip.AddrFromSlice(network.IP)
+		bits, _ := network.Mask.Size()
+		start, end := iprange.PrefixRange(netip.PrefixFrom(ip.Unmap(), bits))
+
+		fields := iprange.Fields{
+			Country:     getMapLang(record.Country.Names, lang),
+			CountryCode: record.Country.IsoCode,
+			City:        getMapLang(record.City.Names, lang),
+			ISP:         record.ISP,
+		}
+		if len(record.Subdivisions) > 0 {
+			fields.Region = getMapLang(record.Subdivisions[0].Names, lang)
+		}
+		if fields.ISP == "" {
+			fields.ISP = record.AutonomousSystemOrganization
+		}
+
+		// adjacent networks with the same location are joined into one range
+		if pending.Start.IsValid() && pending.Fields == fields && pending.End.Next() == start {
+			pending.End = end
+			continue
+		}
+		if err := emit(); err != nil || stopped {
+			return err
+		}
+		pending = iprange.Range{Start: start, End: end, Fields: fields}
+	}
+	if err := networks.Err(); err != nil {
+		return err
+	}
+	return emit()
+}
+
 type Result struct {
 	Country     string `json:"country"`
 	CountryCode string `json:"country_code"`
@@ -75,6 +157,14 @@ func (r Result) String() string {
 
 const DefaultLang = "en"
 
+func selectedLang() string {
+	lang := viper.GetString("selected.lang")
+	if lang == "" {
+		lang = "zh-CN"
+	}
+	return lang
+}
+
 func getMapLang(data map[string]string, lang string) string {
 	res, found := data[lang]
 	if found {
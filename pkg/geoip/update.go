@@ -0,0 +1,67 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"github.com/abc1763613206/nabili/pkg/archive"
+	"github.com/abc1763613206/nabili/pkg/common"
+	"github.com/abc1763613206/nabili/pkg/download"
+)
+
+// MaxMindDownloadUrl is the GeoLite2 permalink, %s is the edition id like GeoLite2-City
+var MaxMindDownloadUrl = "https://download.maxmind.com/geoip/databases/%s/download?suffix=tar.gz"
+
+// DBIPDownloadUrls are templates of DB-IP lite databases, {yyyy} and {mm} are replaced by the release month
+var DBIPDownloadUrls = []string{
+	"https://download.db-ip.com/free/dbip-city-lite-{yyyy}-{mm}.mmdb.gz",
+}
+
+// DBIPASNDownloadUrls are templates of the DB-IP lite ASN database
+var DBIPASNDownloadUrls = []string{
+	"https://download.db-ip.com/free/dbip-asn-lite-{yyyy}-{mm}.mmdb.gz",
+}
+
+const DBIPArchive = archive.TypeGz
+
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// DownloadMaxMind downloads a GeoLite2 database with the MaxMind account id and license key,
+// the edition is taken from the file name, e.g. GeoLite2-City.mmdb or GeoLite2-ASN.mmdb
+func DownloadMaxMind(filePath, accountID, licenseKey string, progress common.ProgressFunc) (data []byte, err error) {
+	if accountID == "" || licenseKey == "" {
+		return nil, errors.New("未配置 MaxMind account-id 与 license-key，请在配置文件 maxmind 中设置或使用环境变量 NALI_MAXMIND_ACCOUNT_ID、NALI_MAXMIND_LICENSE_KEY")
+	}
+
+	edition := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	header := http.Header{}
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(accountID+":"+licenseKey)))
+
+	return download.DownloadWithOptions(filePath, download.Options{
+		Progress: progress,
+		Header:   header,
+		Archive:  archive.TypeTarGz,
+		Member:   edition + ".mmdb",
+		Check:    CheckFile,
+	}, fmt.Sprintf(MaxMindDownloadUrl, edition))
+}
+
+// CheckFile checks the MMDB metadata marker and that the database can be opened
+func CheckFile(data []byte) bool {
+	if !bytes.Contains(data, metadataMarker) {
+		return false
+	}
+	reader, err := geoip2.FromBytes(data)
+	if err != nil {
+		return false
+	}
+	_ = reader.Close()
+	return true
+}
@@ -0,0 +1,98 @@
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abc1763613206/nabili/pkg/download"
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+func tarGz(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadMaxMind(t *testing.T) {
+	var mmdb bytes.Buffer
+	ranges := []iprange.Range{{Start: netip.MustParseAddr("1.0.0.0"), End: netip.MustParseAddr("1.0.0.255"), Fields: iprange.Fields{Country: "中国"}}}
+	if err := Write(&mmdb, ranges, WriteOptions{Lang: "zh-CN"}); err != nil {
+		t.Fatal(err)
+	}
+
+	archives := map[string][]byte{
+		"/geoip/databases/GeoLite2-City/download": tarGz(t, map[string][]byte{
+			"GeoLite2-City_20240102/README.txt":         []byte("readme"),
+			"GeoLite2-City_20240102/GeoLite2-City.mmdb": mmdb.Bytes(),
+		}),
+		"/geoip/databases/GeoLite2-ASN/download": tarGz(t, map[string][]byte{
+			"GeoLite2-ASN_20240102/GeoLite2-ASN.mmdb": []byte("not a database"),
+		}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "42" || pass != "key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		data, found := archives[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	saved := MaxMindDownloadUrl
+	MaxMindDownloadUrl = server.URL + "/geoip/databases/%s/download?suffix=tar.gz"
+	defer func() { MaxMindDownloadUrl = saved }()
+
+	dir := t.TempDir()
+	city := filepath.Join(dir, "GeoLite2-City.mmdb")
+	if _, err := DownloadMaxMind(city, "42", "key", nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(city); !bytes.Equal(data, mmdb.Bytes()) {
+		t.Error("the mmdb member was not saved")
+	}
+
+	// the edition follows the file name and the extracted file is checked
+	asn := filepath.Join(dir, "GeoLite2-ASN.mmdb")
+	if _, err := DownloadMaxMind(asn, "42", "key", nil); !errors.Is(err, download.ErrCheckFailed) {
+		t.Errorf("invalid mmdb: err = %v", err)
+	}
+	if _, err := os.Stat(asn); err == nil {
+		t.Error("an invalid mmdb was saved")
+	}
+
+	if _, err := DownloadMaxMind(city, "42", "wrong", nil); err == nil {
+		t.Error("wrong license key accepted")
+	}
+	if _, err := DownloadMaxMind(city, "", "", nil); err == nil {
+		t.Error("missing credentials accepted")
+	}
+}
@@ -0,0 +1,347 @@
+package geoip
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// WriteOptions are the metadata of a written MMDB file
+type WriteOptions struct {
+	// DatabaseType defaults to GeoIP2-City so that geoip2 readers accept the file
+	DatabaseType string
+	Description  string
+	// Lang is the key of the names maps, e.g. zh-CN
+	Lang string
+}
+
+// mmdb data section types
+const (
+	typeString = 2
+	typeMap    = 7
+	typeUint16 = 5
+	typeUint32 = 6
+	typeUint64 = 9
+	typeArray  = 11
+)
+
+// recordEmpty marks a record without data, records > 0 point to nodes
+// and records < 0 point to data, -1 being the first one
+const recordEmpty = 0
+
+var (
+	ipv4Subtree = netip.MustParsePrefix("::/96")
+	ipv4Mapped  = netip.MustParsePrefix("::ffff:0:0/96")
+)
+
+// Write writes sorted, non-overlapping ranges as an IPv6 MMDB file in the GeoIP2 City layout,
+// IPv4 ranges are stored in ::/96 and aliased from ::ffff:0:0/96
+func Write(w io.Writer, ranges []iprange.Range, opts WriteOptions) error {
+	if opts.DatabaseType == "" {
+		opts.DatabaseType = "GeoIP2-City"
+	}
+	if opts.Lang == "" {
+		opts.Lang = DefaultLang
+	}
+
+	t := &tree{nodes: make([][2]int32, 1)}
+	var data []byte
+	var dataOffsets []int
+	dataIdx := make(map[iprange.Fields]int32)
+
+	hasIPv4 := false
+	for _, r := range ranges {
+		if r.Fields.IsEmpty() {
+			continue
+		}
+		id, found := dataIdx[r.Fields]
+		if !found {
+			dataOffsets = append(dataOffsets, len(data))
+			data = encodeFields(data, r.Fields, opts.Lang)
+			id = int32(-len(dataOffsets))
+			dataIdx[r.Fields] = id
+		}
+
+		for _, prefix := range r.Prefixes() {
+			if prefix.Addr().Is4() {
+				hasIPv4 = true
+				var b [16]byte
+				ip4 := prefix.Addr().As4()
+				copy(b[12:], ip4[:])
+				prefix = netip.PrefixFrom(netip.AddrFrom16(b), prefix.Bits()+96)
+			} else if prefix.Overlaps(ipv4Subtree) || prefix.Overlaps(ipv4Mapped) {
+				// keep IPv6 data out of the IPv4 subtree and its alias
+				for _, p := range excludePrefix(prefix, ipv4Subtree, ipv4Mapped) {
+					t.insert(p, id)
+				}
+				continue
+			}
+			t.insert(prefix, id)
+		}
+	}
+	if hasIPv4 {
+		t.alias(ipv4Mapped, t.nodeAt(ipv4Subtree))
+	}
+
+	nodeCount := len(t.nodes)
+	recordSize := 24
+	maxRecord := nodeCount + 16 + len(data)
+	if maxRecord >= 1<<28 {
+		recordSize = 32
+	} else if maxRecord >= 1<<24 {
+		recordSize = 28
+	}
+	if uint64(maxRecord) >= 1<<32-1 {
+		return errors.New("数据过大，无法写入 MMDB")
+	}
+
+	value := func(record int32) uint32 {
+		switch {
+		case record == recordEmpty:
+			return uint32(nodeCount)
+		case record > 0:
+			return uint32(record)
+		}
+		return uint32(nodeCount + 16 + dataOffsets[-record-1])
+	}
+
+	bw := bufio.NewWriter(w)
+	node := make([]byte, recordSize*2/8)
+	for _, n := range t.nodes {
+		left, right := value(n[0]), value(n[1])
+		switch recordSize {
+		case 24:
+			node[0], node[1], node[2] = byte(left>>16), byte(left>>8), byte(left)
+			node[3], node[4], node[5] = byte(right>>16), byte(right>>8), byte(right)
+		case 28:
+			node[0], node[1], node[2] = byte(left>>16), byte(left>>8), byte(left)
+			node[3] = byte(left>>24&0x0f)<<4 | byte(right>>24&0x0f)
+			node[4], node[5], node[6] = byte(right>>16), byte(right>>8), byte(right)
+		case 32:
+			binary.BigEndian.PutUint32(node, left)
+			binary.BigEndian.PutUint32(node[4:], right)
+		}
+		if _, err := bw.Write(node); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.Write(make([]byte, 16)); err != nil {
+		return err
+	}
+	if _, err := bw.Write(data); err != nil {
+		return err
+	}
+	if _, err := bw.Write(metadataMarker); err != nil {
+		return err
+	}
+	if _, err := bw.Write(encodeMetadata(uint32(nodeCount), uint16(recordSize), opts)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// tree is the binary search tree, the root is node 0
+type tree struct {
+	nodes [][2]int32
+}
+
+func bitAt(b [16]byte, i int) int {
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+func (t *tree) insert(prefix netip.Prefix, record int32) {
+	b := prefix.Addr().As16()
+	n := t.walk(b, prefix.Bits()-1)
+	t.nodes[n][bitAt(b, prefix.Bits()-1)] = record
+}
+
+// nodeAt returns the node at prefix, creating the path if needed
+func (t *tree) nodeAt(prefix netip.Prefix) int32 {
+	return int32(t.walk(prefix.Addr().As16(), prefix.Bits()))
+}
+
+// walk follows the first depth bits of b and returns the reached node,
+// missing nodes are created and wider data records are split
+func (t *tree) walk(b [16]byte, depth int) int {
+	n := 0
+	for i := 0; i < depth; i++ {
+		bit := bitAt(b, i)
+		if r := t.nodes[n][bit]; r <= 0 {
+			t.nodes = append(t.nodes, [2]int32{r, r})
+			t.nodes[n][bit] = int32(len(t.nodes) - 1)
+		}
+		n = int(t.nodes[n][bit])
+	}
+	return n
+}
+
+// alias points the record of prefix to an existing node
+func (t *tree) alias(prefix netip.Prefix, node int32) {
+	t.insert(prefix, node)
+}
+
+// excludePrefix splits prefix into the prefixes not overlapping any of excludes
+func excludePrefix(prefix netip.Prefix, excludes ...netip.Prefix) []netip.Prefix {
+	for _, ex := range excludes {
+		if !prefix.Overlaps(ex) {
+			continue
+		}
+		if prefix.Bits() >= ex.Bits() {
+			return nil
+		}
+		var out []netip.Prefix
+		b := prefix.Addr().As16()
+		for _, half := range []int{0, 1} {
+			h := b
+			if half == 1 {
+				h[prefix.Bits()/8] |= 0x80 >> (prefix.Bits() % 8)
+			}
+			out = append(out, excludePrefix(netip.PrefixFrom(netip.AddrFrom16(h), prefix.Bits()+1), excludes...)...)
+		}
+		return out
+	}
+	return []netip.Prefix{prefix}
+}
+
+func encodeFields(b []byte, f iprange.Fields, lang string) []byte {
+	names := func(b []byte, name string) []byte {
+		b = encodeControl(b, typeMap, 1)
+		b = encodeString(b, "names")
+		b = encodeControl(b, typeMap, 1)
+		b = encodeString(b, lang)
+		return encodeString(b, name)
+	}
+
+	var keys []string
+	if f.Country != "" || f.CountryCode != "" {
+		keys = append(keys, "country")
+	}
+	if f.Region != "" {
+		keys = append(keys, "subdivisions")
+	}
+	if f.City != "" {
+		keys = append(keys, "city")
+	}
+	if f.ISP != "" {
+		keys = append(keys, "isp")
+	}
+
+	b = encodeControl(b, typeMap, len(keys))
+	for _, key := range keys {
+		b = encodeString(b, key)
+		switch key {
+		case "country":
+			size := 0
+			if f.Country != "" {
+				size++
+			}
+			if f.CountryCode != "" {
+				size++
+			}
+			b = encodeControl(b, typeMap, size)
+			if f.CountryCode != "" {
+				b = encodeString(b, "iso_code")
+				b = encodeString(b, f.CountryCode)
+			}
+			if f.Country != "" {
+				b = encodeString(b, "names")
+				b = encodeControl(b, typeMap, 1)
+				b = encodeString(b, lang)
+				b = encodeString(b, f.Country)
+			}
+		case "subdivisions":
+			b = encodeControl(b, typeArray, 1)
+			b = names(b, f.Region)
+		case "city":
+			b = names(b, f.City)
+		case "isp":
+			b = encodeString(b, f.ISP)
+		}
+	}
+	return b
+}
+
+func encodeMetadata(nodeCount uint32, recordSize uint16, opts WriteOptions) []byte {
+	meta := map[string]func(b []byte) []byte{
+		"binary_format_major_version": func(b []byte) []byte { return encodeUint(b, typeUint16, 2) },
+		"binary_format_minor_version": func(b []byte) []byte { return encodeUint(b, typeUint16, 0) },
+		"build_epoch":                 func(b []byte) []byte { return encodeUint(b, typeUint64, uint64(time.Now().Unix())) },
+		"database_type":               func(b []byte) []byte { return encodeString(b, opts.DatabaseType) },
+		"description": func(b []byte) []byte {
+			b = encodeControl(b, typeMap, 1)
+			b = encodeString(b, "en")
+			return encodeString(b, opts.Description)
+		},
+		"ip_version": func(b []byte) []byte { return encodeUint(b, typeUint16, 6) },
+		"languages": func(b []byte) []byte {
+			b = encodeControl(b, typeArray, 1)
+			return encodeString(b, opts.Lang)
+		},
+		"node_count":  func(b []byte) []byte { return encodeUint(b, typeUint32, uint64(nodeCount)) },
+		"record_size": func(b []byte) []byte { return encodeUint(b, typeUint16, uint64(recordSize)) },
+	}
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b := encodeControl(nil, typeMap, len(meta))
+	for _, key := range keys {
+		b = encodeString(b, key)
+		b = meta[key](b)
+	}
+	return b
+}
+
+// encodeControl writes the control byte of a value with its type and size,
+// types above 7 are extended and stored in the following byte
+func encodeControl(b []byte, typ int, size int) []byte {
+	var ctrl byte
+	if typ <= 7 {
+		ctrl = byte(typ) << 5
+	}
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 285:
+		ctrl |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		ctrl |= 30
+		s := size - 285
+		sizeBytes = []byte{byte(s >> 8), byte(s)}
+	default:
+		ctrl |= 31
+		s := size - 65821
+		sizeBytes = []byte{byte(s >> 16), byte(s >> 8), byte(s)}
+	}
+	b = append(b, ctrl)
+	if typ > 7 {
+		b = append(b, byte(typ-7))
+	}
+	return append(b, sizeBytes...)
+}
+
+func encodeString(b []byte, s string) []byte {
+	b = encodeControl(b, typeString, len(s))
+	return append(b, s...)
+}
+
+func encodeUint(b []byte, typ int, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	n := 0
+	for n < 8 && buf[n] == 0 {
+		n++
+	}
+	b = encodeControl(b, typ, 8-n)
+	return append(b, buf[n:]...)
+}
@@ -0,0 +1,106 @@
+package geoip
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+func TestWrite(t *testing.T) {
+	ranges := []iprange.Range{
+		{Start: netip.MustParseAddr("1.0.0.0"), End: netip.MustParseAddr("1.0.0.255"),
+			Fields: iprange.Fields{Country: "中国", CountryCode: "CN", Region: "北京", City: "北京", ISP: "联通"}},
+		{Start: netip.MustParseAddr("1.0.1.0"), End: netip.MustParseAddr("1.0.3.10"),
+			Fields: iprange.Fields{Country: "中国", ISP: "电信"}},
+		{Start: netip.MustParseAddr("::"), End: netip.MustParseAddr("2001:db7:ffff:ffff:ffff:ffff:ffff:ffff"),
+			Fields: iprange.Fields{Country: "保留地址"}},
+		{Start: netip.MustParseAddr("2001:db8::"), End: netip.MustParseAddr("2001:db8::ffff"),
+			Fields: iprange.Fields{Country: "美国", CountryCode: "US"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, ranges, WriteOptions{Lang: "zh-CN", Description: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckFile(buf.Bytes()) {
+		t.Fatal("CheckFile rejected the written file")
+	}
+
+	reader, err := geoip2.FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip      string
+		country string
+		code    string
+		city    string
+	}{
+		{"1.0.0.1", "中国", "CN", "北京"},
+		{"::ffff:1.0.0.1", "中国", "CN", "北京"},
+		{"1.0.3.10", "中国", "", ""},
+		{"1.0.3.11", "", "", ""},
+		{"2001:db8::1", "美国", "US", ""},
+		{"2001:db8::1:0", "", "", ""},
+		{"2000::1", "保留地址", "", ""},
+	}
+	for _, tt := range tests {
+		record, err := reader.City(net.ParseIP(tt.ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Country.Names["zh-CN"] != tt.country || record.Country.IsoCode != tt.code || record.City.Names["zh-CN"] != tt.city {
+			t.Errorf("City(%s) = %v %v %v, want %s %s %s", tt.ip, record.Country.Names, record.Country.IsoCode, record.City.Names, tt.country, tt.code, tt.city)
+		}
+	}
+
+	mmdb, err := maxminddb.FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mmdb.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	var isp struct {
+		ISP string `maxminddb:"isp"`
+	}
+	if err := mmdb.Lookup(net.ParseIP("1.0.2.0"), &isp); err != nil || isp.ISP != "电信" {
+		t.Errorf("isp = %s, %v", isp.ISP, err)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(filePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewGeoIP(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var walked []iprange.Range
+	if err := db.Ranges(func(r iprange.Range) error {
+		walked = append(walked, r)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(walked) == 0 || walked[0] != ranges[0] {
+		t.Fatalf("Ranges() = %v", walked)
+	}
+	last := walked[len(walked)-1]
+	if last.Start != ranges[3].Start || last.End != ranges[3].End {
+		t.Errorf("last range = %v, want %v", last, ranges[3])
+	}
+	for _, r := range walked {
+		if r.Start.Is4() != r.End.Is4() || ipv4Mapped.Contains(r.Start) {
+			t.Errorf("Ranges() walked the IPv4 alias: %v", r)
+		}
+	}
+}
@@ -18,13 +18,13 @@ type IP2Location struct {
 func NewIP2Location(filePath string) (*IP2Location, error) {
 	_, err := os.Stat(filePath)
 	if err != nil && os.IsNotExist(err) {
-		log.Println("文件不存在，请自行下载 IP2Location 库，并保存在", filePath)
+		log.Println("文件不存在，请使用 nabili update --db ip2location 下载，或自行下载 IP2Location 库，并保存在", filePath)
 		return nil, err
 	} else {
 		db, err := ip2location.OpenDB(filePath)
 
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		return &IP2Location{db: db}, nil
 	}
@@ -0,0 +1,102 @@
+package ip2location
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/abc1763613206/nabili/pkg/archive"
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+// DownloadUrl is the IP2Location LITE download api, token and file code are filled in
+var DownloadUrl = "https://www.ip2location.com/download/?token=%s&file=%s"
+
+// DefaultFileCode is the LITE package matching the default IP2LOCATION-LITE-DB3.IPV6.BIN
+const DefaultFileCode = "DB3LITEBINIPV6"
+
+var liteFileRe = regexp.MustCompile(`(?i)^IP2LOCATION-LITE-(DB\d+)(\.IPV6)?\.BIN$`)
+
+// Download downloads an IP2Location LITE database with the download token,
+// the package code is derived from the file name if not specified
+func Download(filePath, token, fileCode string, progress common.ProgressFunc) (data []byte, err error) {
+	if token == "" {
+		return nil, errors.New("未配置 IP2Location 下载 token，请在配置文件 ip2location.token 中设置或使用环境变量 NALI_IP2LOCATION_TOKEN")
+	}
+	if fileCode == "" {
+		fileCode = FileCode(filepath.Base(filePath))
+	}
+
+	zipData, err := common.GetHttpClient().GetWithProgress(progress, fmt.Sprintf(DownloadUrl, url.QueryEscape(token), url.QueryEscape(fileCode)))
+	if err != nil {
+		return nil, err
+	}
+	// errors like "NO PERMISSION" are returned as plain text with status 200
+	if !bytes.HasPrefix(zipData, []byte("PK")) {
+		if len(zipData) > 128 {
+			zipData = zipData[:128]
+		}
+		return nil, fmt.Errorf("IP2Location 下载失败: %s", strings.TrimSpace(string(zipData)))
+	}
+
+	// the BIN in the zip may be named differently from the configured file
+	data, err = archive.Extract(zipData, archive.TypeZip, filepath.Base(filePath))
+	if err != nil {
+		data, err = archive.Extract(zipData, archive.TypeZip, "*.BIN")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !CheckFile(data) {
+		return nil, errors.New("IP2Location 数据库内容出错")
+	}
+
+	return data, common.SaveFile(filePath, data)
+}
+
+// FileCode returns the LITE package code of a BIN file name,
+// e.g. IP2LOCATION-LITE-DB3.IPV6.BIN is DB3LITEBINIPV6
+func FileCode(fileName string) string {
+	m := liteFileRe.FindStringSubmatch(fileName)
+	if m == nil {
+		return DefaultFileCode
+	}
+	code := strings.ToUpper(m[1]) + "LITEBIN"
+	if m[2] != "" {
+		code += "IPV6"
+	}
+	return code
+}
+
+// CheckFile validates the 64 bytes header of an IP2Location BIN database
+func CheckFile(data []byte) bool {
+	if len(data) < 64 {
+		return false
+	}
+
+	dbType, dbColumn := data[0], data[1]
+	year, month, day := data[2], data[3], data[4]
+	ipv4Count := binary.LittleEndian.Uint32(data[5:9])
+	ipv4Addr := binary.LittleEndian.Uint32(data[9:13])
+	productCode := data[29]
+
+	if dbType == 0 || dbType > 26 || dbColumn == 0 {
+		return false
+	}
+	if month == 0 || month > 12 || day == 0 || day > 31 {
+		return false
+	}
+	// only BINs from Jan 2021 onwards have the product code set
+	if year >= 21 && productCode != 1 {
+		return false
+	}
+	if ipv4Count == 0 || ipv4Addr == 0 || uint64(ipv4Addr)+uint64(ipv4Count)*uint64(dbColumn)*4 > uint64(len(data))+1 {
+		return false
+	}
+	return true
+}
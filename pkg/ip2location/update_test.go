@@ -0,0 +1,121 @@
+package ip2location
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileCode(t *testing.T) {
+	for name, want := range map[string]string{
+		"IP2LOCATION-LITE-DB3.IPV6.BIN":  "DB3LITEBINIPV6",
+		"IP2LOCATION-LITE-DB11.BIN":      "DB11LITEBIN",
+		"ip2location-lite-db1.ipv6.bin":  "DB1LITEBINIPV6",
+		"IP2LOCATION-DB3.IPV6.BIN":       DefaultFileCode,
+		"custom.bin":                     DefaultFileCode,
+		"IP2LOCATION-LITE-DB3.IPV6.BIN~": DefaultFileCode,
+	} {
+		if got := FileCode(name); got != want {
+			t.Errorf("FileCode(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+// binFile returns a minimal BIN with a valid header
+func binFile() []byte {
+	data := make([]byte, 128)
+	data[0], data[1] = 3, 4 // DB3, 4 columns
+	data[2], data[3], data[4] = 24, 1, 1
+	binary.LittleEndian.PutUint32(data[5:], 1)
+	binary.LittleEndian.PutUint32(data[9:], 65)
+	data[29] = 1
+	return data
+}
+
+func zipData(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownload(t *testing.T) {
+	if !CheckFile(binFile()) {
+		t.Fatal("CheckFile rejected the test BIN")
+	}
+
+	responses := map[string][]byte{
+		"DB3LITEBINIPV6": zipData(t, map[string][]byte{"README_LITE.TXT": []byte("readme"), "IP2LOCATION-LITE-DB3.IPV6.BIN": binFile()}),
+		// the BIN is named differently from the configured file
+		"DB11LITEBIN": zipData(t, map[string][]byte{"LICENSE_LITE.TXT": []byte("license"), "IP2LOCATION-LITE-DB11.BIN": binFile()}),
+		"DB1LITEBIN":  zipData(t, map[string][]byte{"IP2LOCATION-LITE-DB1.BIN": []byte("not a database")}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "token" {
+			_, _ = w.Write([]byte("INVALID TOKEN"))
+			return
+		}
+		data, found := responses[r.URL.Query().Get("file")]
+		if !found {
+			_, _ = w.Write([]byte("NO PERMISSION\n"))
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	saved := DownloadUrl
+	DownloadUrl = server.URL + "/download/?token=%s&file=%s"
+	defer func() { DownloadUrl = saved }()
+
+	dir := t.TempDir()
+	tests := []struct {
+		file, token, code string
+		err               string
+	}{
+		{"IP2LOCATION-LITE-DB3.IPV6.BIN", "token", "", ""},
+		{"ip2location.bin", "token", "DB11LITEBIN", ""},
+		{"IP2LOCATION-LITE-DB1.BIN", "token", "", "数据库内容出错"},
+		{"IP2LOCATION-LITE-DB5.BIN", "token", "", "IP2Location 下载失败: NO PERMISSION"},
+		{"IP2LOCATION-LITE-DB3.IPV6.BIN", "wrong", "", "IP2Location 下载失败: INVALID TOKEN"},
+		{"IP2LOCATION-LITE-DB3.IPV6.BIN", "", "", "token"},
+	}
+	for _, tt := range tests {
+		filePath := filepath.Join(dir, tt.file)
+		_ = os.Remove(filePath)
+		_, err := Download(filePath, tt.token, tt.code, nil)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Download(%s, %s): err = %v, want %q", tt.file, tt.code, err, tt.err)
+			}
+			if _, err := os.Stat(filePath); err == nil {
+				t.Errorf("Download(%s, %s) saved the file", tt.file, tt.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Download(%s, %s): %v", tt.file, tt.code, err)
+			continue
+		}
+		if data, _ := os.ReadFile(filePath); !bytes.Equal(data, binFile()) {
+			t.Errorf("Download(%s, %s) saved the wrong file", tt.file, tt.code)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package ip2region
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/abc1763613206/nabili/internal/constant"
 	"github.com/abc1763613206/nabili/pkg/download"
+	"github.com/abc1763613206/nabili/pkg/iprange"
 	"github.com/abc1763613206/nabili/pkg/wry"
 
 	"github.com/lionsoul2014/ip2region/binding/golang/xdb"
@@ -24,6 +26,7 @@ var DownloadUrls = []string{
 
 type Ip2Region struct {
 	seacher *xdb.Searcher
+	data    []byte
 }
 
 func NewIp2Region(filePath string) (*Ip2Region, error) {
@@ -61,6 +64,7 @@ func NewIp2Region(filePath string) (*Ip2Region, error) {
 	}
 	return &Ip2Region{
 		seacher: searcher,
+		data:    data,
 	}, nil
 }
 
@@ -82,3 +86,76 @@ func (db Ip2Region) Find(query string, params ...string) (result fmt.Stringer, e
 func (db Ip2Region) Name() string {
 	return "ip2region"
 }
+
+// Ranges walks the segment index blocks, each block is
+// [start ip][end ip][data length 2 bytes][data ptr]
+func (db Ip2Region) Ranges(fn func(iprange.Range) error) error {
+	header, err := xdb.NewHeader(db.data)
+	if err != nil {
+		return err
+	}
+	for p := header.StartIndexPtr; p <= header.EndIndexPtr; p += xdb.SegmentIndexBlockSize {
+		block := db.data[p : p+xdb.SegmentIndexBlockSize]
+		dataLen := uint32(binary.LittleEndian.Uint16(block[8:]))
+		dataPtr := binary.LittleEndian.Uint32(block[10:])
+		if uint64(dataPtr)+uint64(dataLen) > uint64(len(db.data)) {
+			return errors.New("ip2region 数据偏移越界")
+		}
+
+		err := fn(iprange.Range{
+			Start:  iprange.Uint32ToAddr(binary.LittleEndian.Uint32(block)),
+			End:    iprange.Uint32ToAddr(binary.LittleEndian.Uint32(block[4:])),
+			Fields: ParseRegion(string(db.data[dataPtr : dataPtr+dataLen])),
+		})
+		if err == iprange.ErrStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseRegion normalizes a region string, the xdb format is
+// country|area|province|city|isp with 0 for unknown fields
+func ParseRegion(region string) (f iprange.Fields) {
+	parts := strings.Split(region, "|")
+	for i, part := range parts {
+		if part == "0" {
+			parts[i] = ""
+		}
+	}
+	if len(parts) == 4 {
+		// newer data without the area field
+		parts = append(parts[:1], append([]string{""}, parts[1:]...)...)
+	}
+	for len(parts) < 5 {
+		parts = append(parts, "")
+	}
+	return iprange.Fields{
+		Country: parts[0],
+		Region:  parts[2],
+		City:    parts[3],
+		ISP:     parts[4],
+	}
+}
+
+// CheckFile validates the xdb header and index pointers
+func CheckFile(data []byte) bool {
+	if len(data) < xdb.HeaderInfoLength+xdb.VectorIndexRows*xdb.VectorIndexCols*xdb.VectorIndexSize {
+		return false
+	}
+	header, err := xdb.NewHeader(data)
+	if err != nil {
+		return false
+	}
+	if header.IndexPolicy != xdb.VectorIndexPolicy && header.IndexPolicy != xdb.BTreeIndexPolicy {
+		return false
+	}
+	start, end := header.StartIndexPtr, header.EndIndexPtr
+	if start < xdb.HeaderInfoLength || start > end || uint64(end)+xdb.SegmentIndexBlockSize > uint64(len(data)) {
+		return false
+	}
+	return (end-start)%xdb.SegmentIndexBlockSize == 0
+}
@@ -58,3 +58,9 @@ func (db IPIPFree) Find(query string, params ...string) (result fmt.Stringer, er
 func (db IPIPFree) Name() string {
 	return "ipip"
 }
+
+// CheckFile validates the ipdb meta header and file size
+func CheckFile(data []byte) bool {
+	_, err := ipdb.NewCityFromBytes(data)
+	return err == nil
+}
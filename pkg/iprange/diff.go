@@ -0,0 +1,131 @@
+package iprange
+
+import "net/netip"
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a range whose location differs between two databases
+type Change struct {
+	Kind  ChangeKind `json:"type"`
+	Start netip.Addr `json:"start"`
+	End   netip.Addr `json:"end"`
+	Old   *Fields    `json:"old,omitempty"`
+	New   *Fields    `json:"new,omitempty"`
+}
+
+// Diff compares two sorted, non-overlapping range lists and calls fn for every
+// added, removed or changed range, adjacent pieces with the same change are joined
+func Diff(old, new []Range, fn func(Change) error) error {
+	var pending *Change
+	flush := func() error {
+		if pending == nil {
+			return nil
+		}
+		c := *pending
+		pending = nil
+		return fn(c)
+	}
+
+	i, j := 0, 0
+	cur, ok := nextPoint(old, new, i, j, netip.Addr{})
+	for ok {
+		inOld := i < len(old) && !cur.Less(old[i].Start)
+		inNew := j < len(new) && !cur.Less(new[j].Start)
+
+		// the piece ends at the nearest range end or before the nearest range start
+		segEnd := LastAddr(cur)
+		if i < len(old) {
+			segEnd = pieceEnd(segEnd, cur, old[i], inOld)
+		}
+		if j < len(new) {
+			segEnd = pieceEnd(segEnd, cur, new[j], inNew)
+		}
+
+		var c *Change
+		switch {
+		case inOld && inNew:
+			if old[i].Fields != new[j].Fields {
+				c = &Change{Kind: Changed, Old: &old[i].Fields, New: &new[j].Fields}
+			}
+		case inOld:
+			c = &Change{Kind: Removed, Old: &old[i].Fields}
+		default:
+			c = &Change{Kind: Added, New: &new[j].Fields}
+		}
+
+		if c != nil && pending != nil && pending.End.Next() == cur && sameChange(pending, c) {
+			pending.End = segEnd
+		} else {
+			if err := flush(); err != nil {
+				return err
+			}
+			if c != nil {
+				c.Start, c.End = cur, segEnd
+				pending = c
+			}
+		}
+
+		for i < len(old) && !segEnd.Less(old[i].End) {
+			i++
+		}
+		for j < len(new) && !segEnd.Less(new[j].End) {
+			j++
+		}
+		cur, ok = nextPoint(old, new, i, j, segEnd)
+	}
+	return flush()
+}
+
+func pieceEnd(segEnd, cur netip.Addr, r Range, covering bool) netip.Addr {
+	end := r.End
+	if !covering {
+		if r.Start.Is4() != cur.Is4() {
+			return segEnd
+		}
+		end = r.Start.Prev()
+	}
+	if end.Less(segEnd) {
+		return end
+	}
+	return segEnd
+}
+
+// nextPoint returns the lowest address after the given one covered by old[i] or new[j]
+func nextPoint(old, new []Range, i, j int, after netip.Addr) (next netip.Addr, ok bool) {
+	for _, r := range []*Range{rangeAt(old, i), rangeAt(new, j)} {
+		if r == nil {
+			continue
+		}
+		candidate := r.Start
+		if after.IsValid() && !after.Less(candidate) {
+			candidate = after.Next()
+		}
+		if !ok || candidate.Less(next) {
+			next, ok = candidate, true
+		}
+	}
+	return
+}
+
+func rangeAt(ranges []Range, i int) *Range {
+	if i < len(ranges) {
+		return &ranges[i]
+	}
+	return nil
+}
+
+func sameChange(a, b *Change) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	if (a.Old == nil) != (b.Old == nil) || (a.Old != nil && *a.Old != *b.Old) {
+		return false
+	}
+	return (a.New == nil) == (b.New == nil) && (a.New == nil || *a.New == *b.New)
+}
@@ -0,0 +1,86 @@
+package iprange
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	r := func(start, end, country string) Range {
+		return Range{Start: netip.MustParseAddr(start), End: netip.MustParseAddr(end), Fields: Fields{Country: country}}
+	}
+	old := []Range{
+		r("1.0.0.0", "1.0.0.255", "A"),
+		r("1.0.1.0", "1.0.1.255", "B"),
+		r("1.0.2.0", "1.0.2.255", "C"),
+		r("2001:db8::", "2001:db8::ffff", "D"),
+	}
+	new := []Range{
+		r("1.0.0.0", "1.0.0.127", "A"),
+		r("1.0.0.128", "1.0.1.127", "X"),
+		r("1.0.1.128", "1.0.1.255", "B"),
+		r("1.0.3.0", "1.0.3.255", "E"),
+		r("2001:db8::", "2001:db8::ffff", "D"),
+		r("2001:db9::", "2001:db9::1", "F"),
+	}
+
+	want := []struct {
+		kind       ChangeKind
+		start, end string
+		old, new   string
+	}{
+		{Changed, "1.0.0.128", "1.0.0.255", "A", "X"},
+		{Changed, "1.0.1.0", "1.0.1.127", "B", "X"},
+		{Removed, "1.0.2.0", "1.0.2.255", "C", ""},
+		{Added, "1.0.3.0", "1.0.3.255", "", "E"},
+		{Added, "2001:db9::", "2001:db9::1", "", "F"},
+	}
+
+	var got []Change
+	if err := Diff(old, new, func(c Change) error {
+		got = append(got, c)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Diff() = %v, want %d changes", got, len(want))
+	}
+	for i, w := range want {
+		c := got[i]
+		if c.Kind != w.kind || c.Start.String() != w.start || c.End.String() != w.end {
+			t.Errorf("change %d = %s %s-%s, want %s %s-%s", i, c.Kind, c.Start, c.End, w.kind, w.start, w.end)
+		}
+		if (c.Old == nil) != (w.old == "") || (c.Old != nil && c.Old.Country != w.old) {
+			t.Errorf("change %d old = %v, want %s", i, c.Old, w.old)
+		}
+		if (c.New == nil) != (w.new == "") || (c.New != nil && c.New.Country != w.new) {
+			t.Errorf("change %d new = %v, want %s", i, c.New, w.new)
+		}
+	}
+}
+
+func TestPrefixes(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       []string
+	}{
+		{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+		{"1.0.0.1", "1.0.0.6", []string{"1.0.0.1/32", "1.0.0.2/31", "1.0.0.4/31", "1.0.0.6/32"}},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", []string{"::/0"}},
+		{"2001:db8::", "2001:db8:0:1::", []string{"2001:db8::/64", "2001:db8:0:1::/128"}},
+	}
+	for _, tt := range tests {
+		got := Range{Start: netip.MustParseAddr(tt.start), End: netip.MustParseAddr(tt.end)}.Prefixes()
+		if len(got) != len(tt.want) {
+			t.Errorf("Prefixes(%s-%s) = %v, want %v", tt.start, tt.end, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].String() != tt.want[i] {
+				t.Errorf("Prefixes(%s-%s) = %v, want %v", tt.start, tt.end, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package iprange
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// Encoder writes ranges one by one in a text format
+type Encoder interface {
+	Encode(r Range) error
+	Flush() error
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVEncoder writes ranges as CSV rows of start, end and FieldNames
+func NewCSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Encode(r Range) error {
+	if !e.header {
+		e.header = true
+		if err := e.w.Write(append([]string{"start", "end"}, FieldNames...)); err != nil {
+			return err
+		}
+	}
+	row := []string{r.Start.String(), r.End.String()}
+	for _, name := range FieldNames {
+		row = append(row, r.Fields.Get(name))
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonlEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLEncoder writes ranges as one JSON object per line
+func NewJSONLEncoder(w io.Writer) Encoder {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &jsonlEncoder{w: bw, enc: enc}
+}
+
+func (e *jsonlEncoder) Encode(r Range) error {
+	return e.enc.Encode(r)
+}
+
+func (e *jsonlEncoder) Flush() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,165 @@
+package iprange
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+)
+
+// ErrStop can be returned by the callback of Ranges to stop iterating early
+var ErrStop = errors.New("stop iteration")
+
+// Fields are the normalized location fields shared by all database formats
+type Fields struct {
+	Country     string `json:"country"`
+	CountryCode string `json:"country_code,omitempty"`
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	ISP         string `json:"isp,omitempty"`
+}
+
+// FieldNames are the names of Fields used in configs and command line flags
+var FieldNames = []string{"country", "country_code", "region", "city", "isp"}
+
+// Get returns the field by name, see FieldNames
+func (f *Fields) Get(name string) string {
+	switch name {
+	case "country":
+		return f.Country
+	case "country_code":
+		return f.CountryCode
+	case "region":
+		return f.Region
+	case "city":
+		return f.City
+	case "isp":
+		return f.ISP
+	}
+	return ""
+}
+
+// Set sets the field by name and reports whether the name is known
+func (f *Fields) Set(name, value string) bool {
+	switch name {
+	case "country":
+		f.Country = value
+	case "country_code":
+		f.CountryCode = value
+	case "region":
+		f.Region = value
+	case "city":
+		f.City = value
+	case "isp":
+		f.ISP = value
+	default:
+		return false
+	}
+	return true
+}
+
+func (f Fields) IsEmpty() bool {
+	return f == Fields{}
+}
+
+func (f Fields) String() string {
+	parts := make([]string, 0, 4)
+	for _, s := range []string{f.Country, f.Region, f.City, f.ISP} {
+		// skip repeated names like 北京 北京
+		if s == "" || (len(parts) > 0 && parts[len(parts)-1] == s) {
+			continue
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseLocation normalizes a wry style country and area pair,
+// the country may contain region and city separated by – or tabs,
+// e.g. 中国–广东–深圳
+func ParseLocation(country, area string) Fields {
+	parts := strings.FieldsFunc(country, func(r rune) bool {
+		return r == '–' || r == '\t' || r == '|'
+	})
+	var f Fields
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		switch i {
+		case 0:
+			f.Country = part
+		case 1:
+			f.Region = part
+		case 2:
+			f.City = part
+		}
+	}
+	f.ISP = strings.TrimSpace(area)
+	return f
+}
+
+// Location formats the fields as a wry style country and area pair, the reverse of ParseLocation
+func (f Fields) Location() (country, area string) {
+	parts := make([]string, 0, 3)
+	for _, s := range []string{f.Country, f.Region, f.City} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "–"), f.ISP
+}
+
+// Range is an inclusive address range of a single family
+type Range struct {
+	Start netip.Addr `json:"start"`
+	End   netip.Addr `json:"end"`
+	Fields
+}
+
+// Contains reports whether ip is in the range
+func (r Range) Contains(ip netip.Addr) bool {
+	return r.Start.Compare(ip) <= 0 && ip.Compare(r.End) <= 0
+}
+
+// Iterable is implemented by databases whose ranges can be walked in address order
+type Iterable interface {
+	// Ranges calls fn for every range in ascending order, IPv4 ranges come first,
+	// iteration stops at the first error, ErrStop is not returned
+	Ranges(fn func(Range) error) error
+}
+
+// Uint32ToAddr converts a big endian uint32 to an IPv4 address
+func Uint32ToAddr(ip uint32) netip.Addr {
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)})
+}
+
+// LastAddr returns the last address of the family of ip
+func LastAddr(ip netip.Addr) netip.Addr {
+	if ip.Is4() {
+		return netip.AddrFrom4([4]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	var b [16]byte
+	for i := range b {
+		b[i] = 0xff
+	}
+	return netip.AddrFrom16(b)
+}
+
+// PrefixRange returns the first and last address of prefix
+func PrefixRange(prefix netip.Prefix) (start, end netip.Addr) {
+	prefix = prefix.Masked()
+	start = prefix.Addr()
+	b := start.AsSlice()
+	bits := prefix.Bits()
+	for i := range b {
+		switch {
+		case bits >= 8:
+			bits -= 8
+		case bits > 0:
+			b[i] |= 0xff >> bits
+			bits = 0
+		default:
+			b[i] = 0xff
+		}
+	}
+	end, _ = netip.AddrFromSlice(b)
+	return
+}
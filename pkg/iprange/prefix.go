@@ -0,0 +1,87 @@
+package iprange
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net/netip"
+)
+
+// u128 is an address as a 128 bit integer, IPv4 addresses use the low 32 bits
+type u128 struct {
+	hi, lo uint64
+}
+
+func toU128(ip netip.Addr) u128 {
+	b := ip.As16()
+	if ip.Is4() {
+		return u128{lo: uint64(binary.BigEndian.Uint32(b[12:]))}
+	}
+	return u128{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+func (u u128) addr(is4 bool) netip.Addr {
+	if is4 {
+		return Uint32ToAddr(uint32(u.lo))
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+	return netip.AddrFrom16(b)
+}
+
+func (u u128) less(v u128) bool {
+	return u.hi < v.hi || (u.hi == v.hi && u.lo < v.lo)
+}
+
+func (u u128) trailingZeros() int {
+	if u.lo != 0 {
+		return bits.TrailingZeros64(u.lo)
+	}
+	return 64 + bits.TrailingZeros64(u.hi)
+}
+
+// or sets the low n bits
+func (u u128) or(n int) u128 {
+	switch {
+	case n >= 128:
+		return u128{^uint64(0), ^uint64(0)}
+	case n >= 64:
+		return u128{u.hi | (1<<(n-64) - 1), ^uint64(0)}
+	}
+	return u128{u.hi, u.lo | (1<<n - 1)}
+}
+
+func (u u128) next() u128 {
+	if u.lo == ^uint64(0) {
+		return u128{u.hi + 1, 0}
+	}
+	return u128{u.hi, u.lo + 1}
+}
+
+// Prefixes splits the range into the fewest CIDR prefixes covering it exactly
+func (r Range) Prefixes() []netip.Prefix {
+	is4 := r.Start.Is4()
+	bitLen := r.Start.BitLen()
+	start, end := toU128(r.Start), toU128(r.End)
+	if end.less(start) {
+		return nil
+	}
+
+	var prefixes []netip.Prefix
+	for {
+		size := start.trailingZeros()
+		if size > bitLen {
+			size = bitLen
+		}
+		for size > 0 && end.less(start.or(size)) {
+			size--
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(start.addr(is4), bitLen-size))
+
+		last := start.or(size)
+		if last == end {
+			return prefixes
+		}
+		start = last.next()
+	}
+}
@@ -0,0 +1,322 @@
+// Package lineedit reads lines from a terminal with emacs style editing keys,
+// history and tab completion. Raw terminal mode is supported on Linux and the BSDs,
+// elsewhere or if the input is not a terminal, lines are read as they are.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+// ErrInterrupt is returned by ReadLine when Ctrl-C is pressed
+var ErrInterrupt = errors.New("interrupt")
+
+// historySize is the number of lines kept in the history
+const historySize = 1000
+
+// Editor reads lines with a prompt
+type Editor struct {
+	Prompt string
+	// Complete returns the candidates to replace head, the line before the cursor, with
+	Complete func(head string) []string
+
+	in       *bufio.Reader
+	out      io.Writer
+	fd       uintptr
+	terminal bool
+
+	history     []string
+	historyFile string
+}
+
+// New returns an editor reading from in, editing keys work if in is a terminal
+func New(in *os.File, out io.Writer) *Editor {
+	return &Editor{
+		in:       bufio.NewReader(in),
+		out:      out,
+		fd:       in.Fd(),
+		terminal: isTerminal(in.Fd()),
+	}
+}
+
+// LoadHistory reads the history from path, lines read later are appended to it.
+// A missing file is not an error.
+func (e *Editor) LoadHistory(path string) error {
+	e.historyFile = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for _, line := range lines {
+		e.addHistory(line)
+	}
+	// keep the file from growing forever
+	if len(lines) > 2*historySize {
+		return os.WriteFile(path, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+	}
+	return nil
+}
+
+// AddHistory adds line to the history and appends it to the history file
+func (e *Editor) AddHistory(line string) error {
+	if !e.addHistory(line) || e.historyFile == "" {
+		return nil
+	}
+	f, err := os.OpenFile(e.historyFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(line + "\n")
+	return err
+}
+
+func (e *Editor) addHistory(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.ContainsAny(line, "\r\n") {
+		return false
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return false
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+	return true
+}
+
+// ReadLine reads a line without its line ending, io.EOF is returned on Ctrl-D
+// or at the end of the input and ErrInterrupt on Ctrl-C
+func (e *Editor) ReadLine() (string, error) {
+	if !e.terminal {
+		return e.readPlain()
+	}
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return e.readPlain()
+	}
+	defer restore()
+	return e.edit()
+}
+
+func (e *Editor) readPlain() (string, error) {
+	if e.terminal {
+		fmt.Fprint(e.out, e.Prompt)
+	}
+	line, err := e.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// edit reads keys until Enter is pressed
+func (e *Editor) edit() (string, error) {
+	var (
+		buf   []rune
+		pos   int
+		hist  = len(e.history)
+		saved []rune // the edited line while browsing the history
+	)
+	refresh := func() {
+		s := "\r" + e.Prompt + string(buf) + "\x1b[K"
+		if w := common.DisplayWidth(string(buf[pos:])); w > 0 {
+			s += fmt.Sprintf("\x1b[%dD", w)
+		}
+		fmt.Fprint(e.out, s)
+	}
+	setLine := func(line []rune) {
+		buf = append([]rune(nil), line...)
+		pos = len(buf)
+	}
+	refresh()
+
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			fmt.Fprint(e.out, "\r\n")
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupt
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(buf)
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+			}
+		case 6: // Ctrl-F
+			if pos < len(buf) {
+				pos++
+			}
+		case 8, 127: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+		case 21: // Ctrl-U
+			buf = append([]rune(nil), buf[pos:]...)
+			pos = 0
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && unicode.IsSpace(buf[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(buf[start-1]) {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+		case 12: // Ctrl-L
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case 16, 14: // Ctrl-P, Ctrl-N
+			hist, saved = e.browse(r == 16, hist, saved, buf, setLine)
+		case '\t':
+			if line, ok := e.complete(buf, pos); ok {
+				buf = append(line, buf[pos:]...)
+				pos = len(line)
+			}
+		case 27: // escape sequences of the arrow and editing keys
+			switch e.readEscape() {
+			case "[A", "OA":
+				hist, saved = e.browse(true, hist, saved, buf, setLine)
+			case "[B", "OB":
+				hist, saved = e.browse(false, hist, saved, buf, setLine)
+			case "[C", "OC":
+				if pos < len(buf) {
+					pos++
+				}
+			case "[D", "OD":
+				if pos > 0 {
+					pos--
+				}
+			case "[H", "OH", "[1~", "[7~":
+				pos = 0
+			case "[F", "OF", "[4~", "[8~":
+				pos = len(buf)
+			case "[3~":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if unicode.IsControl(r) {
+				continue
+			}
+			buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+			pos++
+		}
+		refresh()
+	}
+}
+
+// browse moves up or down in the history
+func (e *Editor) browse(up bool, hist int, saved, buf []rune, setLine func([]rune)) (int, []rune) {
+	switch {
+	case up && hist > 0:
+		if hist == len(e.history) {
+			saved = append([]rune(nil), buf...)
+		}
+		hist--
+		setLine([]rune(e.history[hist]))
+	case !up && hist < len(e.history):
+		hist++
+		if hist == len(e.history) {
+			setLine(saved)
+		} else {
+			setLine([]rune(e.history[hist]))
+		}
+	}
+	return hist, saved
+}
+
+// readEscape reads the rest of an escape sequence after ESC, like [A or [3~
+func (e *Editor) readEscape() string {
+	b, err := e.in.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return ""
+	}
+	seq := []byte{b}
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, c)
+		if c >= 0x40 && c <= 0x7e {
+			return string(seq)
+		}
+	}
+}
+
+// complete returns the line before the cursor extended by the common prefix of
+// the candidates, the candidates are listed if there is nothing to extend
+func (e *Editor) complete(buf []rune, pos int) ([]rune, bool) {
+	if e.Complete == nil {
+		return nil, false
+	}
+	head := string(buf[:pos])
+	candidates := e.Complete(head)
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		prefix = commonPrefix(prefix, c)
+	}
+	if len(candidates) == 1 && !strings.HasSuffix(prefix, ",") {
+		prefix += " "
+	}
+	if len(prefix) > len(head) {
+		return []rune(prefix), true
+	}
+
+	// list the words being completed
+	words := make([]string, len(candidates))
+	for i, c := range candidates {
+		words[i] = c[strings.LastIndexAny(prefix, " ,")+1:]
+	}
+	fmt.Fprint(e.out, "\r\n"+strings.Join(words, "  ")+"\r\n")
+	return nil, false
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	// do not split a UTF-8 sequence
+	for i > 0 && i < len(a) && a[i]&0xc0 == 0x80 {
+		i--
+	}
+	return a[:i]
+}
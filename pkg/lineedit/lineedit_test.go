@@ -0,0 +1,96 @@
+package lineedit
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(keys string) *Editor {
+	return &Editor{in: bufio.NewReader(strings.NewReader(keys)), out: io.Discard}
+}
+
+func TestEdit(t *testing.T) {
+	for keys, want := range map[string]string{
+		"abc\r":                    "abc",
+		"ab\x1b[Dc\r":              "acb",
+		"abc\x01x\x05y\r":          "xabcy",
+		"abc\x7f\x7fd\r":           "ad",
+		"abc\x02\x02\x0b\r":        "a",
+		"abc def\x17\r":            "abc ",
+		"abc\x1b[D\x15\r":          "c",
+		"中国\x1b[Dx\x1b[3~\r":       "中x",
+		"ab\x1b[H\x1b[3~\x1b[F!\r": "b!",
+	} {
+		got, err := newTestEditor(keys).edit()
+		if err != nil || got != want {
+			t.Errorf("edit(%q) = %q, %v, want %q", keys, got, err, want)
+		}
+	}
+
+	if _, err := newTestEditor("ab\x03").edit(); err != ErrInterrupt {
+		t.Errorf("Ctrl-C = %v, want ErrInterrupt", err)
+	}
+	if _, err := newTestEditor("\x04").edit(); err != io.EOF {
+		t.Errorf("Ctrl-D = %v, want io.EOF", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("1.1.1.1\n:json on\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEditor("")
+	if err := e.LoadHistory(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"8.8.8.8", "8.8.8.8", " ", ":info"} {
+		if err := e.AddHistory(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if got := string(data); got != "1.1.1.1\n:json on\n8.8.8.8\n:info\n" {
+		t.Errorf("history file = %q", got)
+	}
+
+	// up twice, down once, then edit the line
+	e.in = bufio.NewReader(strings.NewReader("\x1b[A\x1b[A\x1b[B!\r"))
+	if got, _ := e.edit(); got != ":info!" {
+		t.Errorf("browsed line = %q", got)
+	}
+	// the edited line is back after browsing down
+	e.in = bufio.NewReader(strings.NewReader("new\x10\x0e\r"))
+	if got, _ := e.edit(); got != "new" {
+		t.Errorf("restored line = %q", got)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	words := []string{":db4", ":db6", ":info"}
+	complete := func(head string) []string {
+		var candidates []string
+		for _, w := range words {
+			if strings.HasPrefix(w, head) {
+				candidates = append(candidates, w)
+			}
+		}
+		return candidates
+	}
+	for keys, want := range map[string]string{
+		":i\t\r":    ":info ",
+		":d\t\r":    ":db",
+		":d\t\t4\r": ":db4",
+		":x\t\r":    ":x",
+	} {
+		e := newTestEditor(keys)
+		e.Complete = complete
+		if got, _ := e.edit(); got != want {
+			t.Errorf("edit(%q) = %q, want %q", keys, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package nbdb
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// Builder merges the ranges of several databases into one range list,
+// each field is taken from the first source in its precedence that has a value
+type Builder struct {
+	sources []string
+	spans   [][]span
+
+	records   []iprange.Fields
+	recordIdx map[iprange.Fields]int
+
+	// field name -> source indexes, all sources in adding order if not set
+	precedence map[string][]int
+}
+
+type span struct {
+	start, end netip.Addr
+	rec        int
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		recordIdx:  make(map[iprange.Fields]int),
+		precedence: make(map[string][]int),
+	}
+}
+
+// Add walks all ranges of a source, sources added first have a higher precedence by default
+func (b *Builder) Add(name string, src iprange.Iterable) error {
+	var spans []span
+	err := src.Ranges(func(r iprange.Range) error {
+		if r.Fields.IsEmpty() || !r.Start.IsValid() || r.Start.Is4() != r.End.Is4() || r.End.Less(r.Start) {
+			return nil
+		}
+		rec := b.record(r.Fields)
+		// adjacent networks of mmdb often share the same location
+		if n := len(spans); n > 0 && spans[n-1].rec == rec && spans[n-1].end.Next() == r.Start {
+			spans[n-1].end = r.End
+			return nil
+		}
+		spans = append(spans, span{start: r.Start, end: r.End, rec: rec})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("遍历 %s 失败: %w", name, err)
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].start.Less(spans[j].start)
+	})
+	// drop overlapping parts, the earlier range wins
+	clean := spans[:0]
+	for _, s := range spans {
+		if n := len(clean); n > 0 && !clean[n-1].end.Less(s.start) {
+			if !clean[n-1].end.Less(s.end) {
+				continue
+			}
+			s.start = clean[n-1].end.Next()
+		}
+		clean = append(clean, s)
+	}
+
+	b.sources = append(b.sources, name)
+	b.spans = append(b.spans, clean)
+	return nil
+}
+
+// SetPrecedence sets the sources a field is taken from, in order,
+// sources not listed never contribute to the field
+func (b *Builder) SetPrecedence(field string, sources ...string) error {
+	if !(&iprange.Fields{}).Set(field, "") {
+		return fmt.Errorf("未知字段 %s，可用字段: %v", field, iprange.FieldNames)
+	}
+	var idx []int
+	for _, name := range sources {
+		found := false
+		for i, source := range b.sources {
+			if source == name {
+				idx = append(idx, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("字段 %s 的来源 %s 未被添加", field, name)
+		}
+	}
+	b.precedence[field] = idx
+	return nil
+}
+
+// Sources returns the names of the added sources
+func (b *Builder) Sources() []string {
+	return b.sources
+}
+
+// Build splits the address space at every range boundary of every source
+// and merges the fields of each piece
+func (b *Builder) Build() []iprange.Range {
+	var out []iprange.Range
+	pos := make([]int, len(b.spans))
+	covering := make([]int, len(b.spans))
+	orders := b.fieldOrders()
+
+	cur, ok := b.nextPoint(pos, netip.Addr{})
+	for ok {
+		// the piece ends right before the nearest boundary after cur
+		last := iprange.LastAddr(cur)
+		segEnd := last
+		for i, spans := range b.spans {
+			covering[i] = -1
+			if pos[i] >= len(spans) {
+				continue
+			}
+			s := spans[pos[i]]
+			boundary := s.start
+			if !cur.Less(s.start) {
+				covering[i] = s.rec
+				if s.end == last {
+					continue
+				}
+				boundary = s.end.Next()
+			}
+			if boundary.Is4() == cur.Is4() && boundary.Prev().Less(segEnd) {
+				segEnd = boundary.Prev()
+			}
+		}
+
+		fields := b.merge(orders, covering)
+		if n := len(out); n > 0 && out[n-1].Fields == fields && out[n-1].End.Next() == cur {
+			out[n-1].End = segEnd
+		} else if !fields.IsEmpty() {
+			out = append(out, iprange.Range{Start: cur, End: segEnd, Fields: fields})
+		}
+
+		cur, ok = b.nextPoint(pos, segEnd)
+	}
+	return out
+}
+
+// nextPoint skips the spans ending at or before after and returns the lowest
+// address after it that is covered by any source
+func (b *Builder) nextPoint(pos []int, after netip.Addr) (next netip.Addr, ok bool) {
+	for i, spans := range b.spans {
+		for after.IsValid() && pos[i] < len(spans) && !after.Less(spans[pos[i]].end) {
+			pos[i]++
+		}
+		if pos[i] >= len(spans) {
+			continue
+		}
+		candidate := spans[pos[i]].start
+		if after.IsValid() && !after.Less(candidate) {
+			candidate = after.Next()
+		}
+		if !ok || candidate.Less(next) {
+			next, ok = candidate, true
+		}
+	}
+	return
+}
+
+// fieldOrders returns the source precedence of each field in iprange.FieldNames order
+func (b *Builder) fieldOrders() [][]int {
+	all := make([]int, len(b.sources))
+	for i := range all {
+		all[i] = i
+	}
+	orders := make([][]int, len(iprange.FieldNames))
+	for i, name := range iprange.FieldNames {
+		if order, found := b.precedence[name]; found {
+			orders[i] = order
+		} else {
+			orders[i] = all
+		}
+	}
+	return orders
+}
+
+func (b *Builder) merge(orders [][]int, covering []int) (fields iprange.Fields) {
+	for f, name := range iprange.FieldNames {
+		for _, i := range orders[f] {
+			if covering[i] < 0 {
+				continue
+			}
+			if v := b.records[covering[i]].Get(name); v != "" {
+				fields.Set(name, v)
+				break
+			}
+		}
+	}
+	return
+}
+
+func (b *Builder) record(f iprange.Fields) int {
+	if id, found := b.recordIdx[f]; found {
+		return id
+	}
+	id := len(b.records)
+	b.records = append(b.records, f)
+	b.recordIdx[f] = id
+	return id
+}
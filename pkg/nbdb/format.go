@@ -0,0 +1,148 @@
+package nbdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// File layout, integers are little endian and IPs are in network byte order:
+//
+//	[header 40 bytes]
+//	[string offsets (string count + 1) * 4][string data]
+//	[records record count * field count * 4] string ids in iprange.FieldNames order
+//	[IPv4 index v4 count * 12] [start 4][end 4][record id 4]
+//	[IPv6 index v6 count * 36] [start 16][end 16][record id 4]
+const (
+	Magic   = "NBDB"
+	Version = 1
+
+	headerLen   = 40
+	v4EntryLen  = 4 + 4 + 4
+	v6EntryLen  = 16 + 16 + 4
+	fieldsCount = 5
+)
+
+type header struct {
+	Version     uint16
+	FieldCount  uint8
+	_           uint8
+	BuildTime   int64
+	SourcesID   uint32
+	StringCount uint32
+	RecordCount uint32
+	V4Count     uint32
+	V6Count     uint32
+	_           uint32
+}
+
+// Write writes sorted, non-overlapping ranges as a nbdb file,
+// strings and field tuples are interned so each one is stored once
+func Write(w io.Writer, ranges []iprange.Range, sources []string) error {
+	strs := newInterner()
+	sourcesID := strs.id(strings.Join(sources, ","))
+
+	records := make([][fieldsCount]uint32, 0)
+	recordIdx := make(map[iprange.Fields]uint32)
+	recordOf := func(f iprange.Fields) uint32 {
+		if id, found := recordIdx[f]; found {
+			return id
+		}
+		var rec [fieldsCount]uint32
+		for i, name := range iprange.FieldNames {
+			rec[i] = strs.id(f.Get(name))
+		}
+		id := uint32(len(records))
+		records = append(records, rec)
+		recordIdx[f] = id
+		return id
+	}
+
+	var v4, v6 []byte
+	for _, r := range ranges {
+		rec := binary.LittleEndian.AppendUint32(nil, recordOf(r.Fields))
+		if r.Start.Is4() {
+			v4 = append(v4, r.Start.AsSlice()...)
+			v4 = append(v4, r.End.AsSlice()...)
+			v4 = append(v4, rec...)
+		} else {
+			v6 = append(v6, r.Start.AsSlice()...)
+			v6 = append(v6, r.End.AsSlice()...)
+			v6 = append(v6, rec...)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(Magic); err != nil {
+		return err
+	}
+	h := header{
+		Version:     Version,
+		FieldCount:  fieldsCount,
+		BuildTime:   time.Now().Unix(),
+		SourcesID:   sourcesID,
+		StringCount: uint32(len(strs.list)),
+		RecordCount: uint32(len(records)),
+		V4Count:     uint32(len(v4) / v4EntryLen),
+		V6Count:     uint32(len(v6) / v6EntryLen),
+	}
+	if err := binary.Write(bw, binary.LittleEndian, h); err != nil {
+		return err
+	}
+
+	offset := uint32(0)
+	for _, s := range strs.list {
+		if err := binary.Write(bw, binary.LittleEndian, offset); err != nil {
+			return err
+		}
+		offset += uint32(len(s))
+	}
+	if err := binary.Write(bw, binary.LittleEndian, offset); err != nil {
+		return err
+	}
+	for _, s := range strs.list {
+		if _, err := bw.WriteString(s); err != nil {
+			return err
+		}
+	}
+	if err := binary.Write(bw, binary.LittleEndian, records); err != nil {
+		return err
+	}
+	if _, err := bw.Write(v4); err != nil {
+		return err
+	}
+	if _, err := bw.Write(v6); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type interner struct {
+	list []string
+	idx  map[string]uint32
+}
+
+func newInterner() *interner {
+	// id 0 is always the empty string
+	return &interner{list: []string{""}, idx: map[string]uint32{"": 0}}
+}
+
+func (in *interner) id(s string) uint32 {
+	if id, found := in.idx[s]; found {
+		return id
+	}
+	id := uint32(len(in.list))
+	in.list = append(in.list, s)
+	in.idx[s] = id
+	return id
+}
+
+func addrFrom(b []byte) netip.Addr {
+	ip, _ := netip.AddrFromSlice(b)
+	return ip
+}
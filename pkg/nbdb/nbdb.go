@@ -0,0 +1,193 @@
+package nbdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+var ErrInvalidFile = errors.New("nbdb 数据库格式错误")
+
+// NBDB is a sorted range database built by nabili db build
+type NBDB struct {
+	data []byte
+	h    header
+
+	strOffsets []byte
+	strData    []byte
+	records    []byte
+	v4         []byte
+	v6         []byte
+}
+
+func NewNBDB(filePath string) (*NBDB, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Println("文件不存在，请使用 nabili db build 生成", filePath)
+		}
+		return nil, err
+	}
+	return FromBytes(data)
+}
+
+// FromBytes opens a nbdb database from its content
+func FromBytes(data []byte) (*NBDB, error) {
+	if len(data) < headerLen || string(data[:4]) != Magic {
+		return nil, ErrInvalidFile
+	}
+	db := &NBDB{data: data}
+	if err := binary.Read(bytes.NewReader(data[4:headerLen]), binary.LittleEndian, &db.h); err != nil {
+		return nil, err
+	}
+	if db.h.Version != Version || db.h.FieldCount < fieldsCount {
+		return nil, fmt.Errorf("不支持的 nbdb 版本 %d", db.h.Version)
+	}
+
+	rest := data[headerLen:]
+	take := func(n uint64) []byte {
+		if rest == nil || n > uint64(len(rest)) {
+			rest = nil
+			return nil
+		}
+		b := rest[:n]
+		rest = rest[n:]
+		return b
+	}
+	db.strOffsets = take((uint64(db.h.StringCount) + 1) * 4)
+	if db.strOffsets == nil {
+		return nil, ErrInvalidFile
+	}
+	db.strData = take(uint64(db.stringOffset(db.h.StringCount)))
+	db.records = take(uint64(db.h.RecordCount) * uint64(db.h.FieldCount) * 4)
+	db.v4 = take(uint64(db.h.V4Count) * v4EntryLen)
+	db.v6 = take(uint64(db.h.V6Count) * v6EntryLen)
+	if rest == nil || len(rest) != 0 {
+		return nil, ErrInvalidFile
+	}
+	return db, nil
+}
+
+func (db *NBDB) Find(query string, params ...string) (result fmt.Stringer, err error) {
+	ip, err := netip.ParseAddr(query)
+	if err != nil {
+		return nil, errors.New("query should be valid IP")
+	}
+	ip = ip.Unmap()
+
+	index, entryLen, count := db.v6, v6EntryLen, int(db.h.V6Count)
+	if ip.Is4() {
+		index, entryLen, count = db.v4, v4EntryLen, int(db.h.V4Count)
+	}
+	ipb := ip.AsSlice()
+	ipLen := len(ipb)
+
+	// the last range starting at or before ip
+	i := sort.Search(count, func(i int) bool {
+		return bytes.Compare(index[i*entryLen:i*entryLen+ipLen], ipb) > 0
+	}) - 1
+	if i < 0 {
+		return nil, errors.New("query not found")
+	}
+	entry := index[i*entryLen : (i+1)*entryLen]
+	if bytes.Compare(ipb, entry[ipLen:2*ipLen]) > 0 {
+		return nil, errors.New("query not found")
+	}
+	fields, err := db.record(binary.LittleEndian.Uint32(entry[2*ipLen:]))
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (db *NBDB) Name() string {
+	return "nbdb"
+}
+
+// Ranges walks the IPv4 index and then the IPv6 index
+func (db *NBDB) Ranges(fn func(iprange.Range) error) error {
+	for _, index := range []struct {
+		data     []byte
+		entryLen int
+		ipLen    int
+	}{{db.v4, v4EntryLen, 4}, {db.v6, v6EntryLen, 16}} {
+		for i := 0; i < len(index.data); i += index.entryLen {
+			entry := index.data[i : i+index.entryLen]
+			fields, err := db.record(binary.LittleEndian.Uint32(entry[2*index.ipLen:]))
+			if err != nil {
+				return err
+			}
+			err = fn(iprange.Range{
+				Start:  addrFrom(entry[:index.ipLen]),
+				End:    addrFrom(entry[index.ipLen : 2*index.ipLen]),
+				Fields: fields,
+			})
+			if err == iprange.ErrStop {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sources returns the names of the databases the file was built from
+func (db *NBDB) Sources() []string {
+	s, _ := db.string(db.h.SourcesID)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// BuildTime returns when the file was built
+func (db *NBDB) BuildTime() time.Time {
+	return time.Unix(db.h.BuildTime, 0)
+}
+
+func (db *NBDB) record(id uint32) (f iprange.Fields, err error) {
+	if id >= db.h.RecordCount {
+		return f, ErrInvalidFile
+	}
+	rec := db.records[int(id)*int(db.h.FieldCount)*4:]
+	for i, name := range iprange.FieldNames {
+		s, err := db.string(binary.LittleEndian.Uint32(rec[i*4:]))
+		if err != nil {
+			return f, err
+		}
+		f.Set(name, s)
+	}
+	return f, nil
+}
+
+func (db *NBDB) string(id uint32) (string, error) {
+	if id >= db.h.StringCount {
+		return "", ErrInvalidFile
+	}
+	start, end := db.stringOffset(id), db.stringOffset(id+1)
+	if start > end || int(end) > len(db.strData) {
+		return "", ErrInvalidFile
+	}
+	return string(db.strData[start:end]), nil
+}
+
+func (db *NBDB) stringOffset(id uint32) uint32 {
+	return binary.LittleEndian.Uint32(db.strOffsets[id*4:])
+}
+
+// CheckFile checks the header and that the section sizes match the file size
+func CheckFile(data []byte) bool {
+	_, err := FromBytes(data)
+	return err == nil
+}
@@ -0,0 +1,131 @@
+package nbdb
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+type sliceSource []iprange.Range
+
+func (s sliceSource) Ranges(fn func(iprange.Range) error) error {
+	for _, r := range s {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rng(start, end string, f iprange.Fields) iprange.Range {
+	return iprange.Range{Start: netip.MustParseAddr(start), End: netip.MustParseAddr(end), Fields: f}
+}
+
+func TestBuildAndFind(t *testing.T) {
+	cn := sliceSource{
+		rng("1.0.0.0", "1.0.0.255", iprange.Fields{Country: "中国", Region: "北京", ISP: "联通"}),
+		rng("1.0.1.0", "1.0.1.255", iprange.Fields{Country: "中国", Region: "北京", ISP: "联通"}),
+		rng("2001:db8::", "2001:db8:0:ffff:ffff:ffff:ffff:ffff", iprange.Fields{Country: "中国", ISP: "电信"}),
+	}
+	geo := sliceSource{
+		rng("1.0.0.0", "1.0.0.127", iprange.Fields{Country: "China", CountryCode: "CN", City: "Beijing"}),
+		rng("1.0.0.128", "1.0.3.255", iprange.Fields{Country: "China", CountryCode: "CN"}),
+		rng("8.8.8.0", "8.8.8.255", iprange.Fields{Country: "United States", CountryCode: "US"}),
+	}
+
+	b := NewBuilder()
+	if err := b.Add("cn", cn); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add("geo", geo); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetPrecedence("city", "geo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetPrecedence("unknown", "geo"); err == nil {
+		t.Error("SetPrecedence accepted an unknown field")
+	}
+
+	want := []iprange.Range{
+		rng("1.0.0.0", "1.0.0.127", iprange.Fields{Country: "中国", CountryCode: "CN", Region: "北京", City: "Beijing", ISP: "联通"}),
+		rng("1.0.0.128", "1.0.1.255", iprange.Fields{Country: "中国", CountryCode: "CN", Region: "北京", ISP: "联通"}),
+		rng("1.0.2.0", "1.0.3.255", iprange.Fields{Country: "China", CountryCode: "CN"}),
+		rng("8.8.8.0", "8.8.8.255", iprange.Fields{Country: "United States", CountryCode: "US"}),
+		rng("2001:db8::", "2001:db8:0:ffff:ffff:ffff:ffff:ffff", iprange.Fields{Country: "中国", ISP: "电信"}),
+	}
+	got := b.Build()
+	if len(got) != len(want) {
+		t.Fatalf("Build() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("range %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, got, b.Sources()); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckFile(buf.Bytes()) {
+		t.Fatal("CheckFile rejected the written file")
+	}
+	db, err := FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"1.0.0.1", "中国 北京 Beijing 联通"},
+		{"1.0.1.255", "中国 北京 联通"},
+		{"1.0.3.0", "China"},
+		{"::ffff:8.8.8.8", "United States"},
+		{"2001:db8::1", "中国 电信"},
+		{"0.0.0.1", ""},
+		{"1.0.4.0", ""},
+		{"2001:db9::", ""},
+	}
+	for _, tt := range tests {
+		res, err := db.Find(tt.query)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Find(%s) = %v, want not found", tt.query, res)
+			}
+			continue
+		}
+		if err != nil || res.String() != tt.want {
+			t.Errorf("Find(%s) = %v, %v, want %s", tt.query, res, err, tt.want)
+		}
+	}
+
+	var walked []iprange.Range
+	_ = db.Ranges(func(r iprange.Range) error {
+		walked = append(walked, r)
+		return nil
+	})
+	if len(walked) != len(want) || walked[4] != want[4] {
+		t.Errorf("Ranges() = %v, want %v", walked, want)
+	}
+	if sources := db.Sources(); len(sources) != 2 || sources[0] != "cn" {
+		t.Errorf("Sources() = %v", sources)
+	}
+}
+
+func TestCheckFileTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, []iprange.Range{rng("1.0.0.0", "1.0.0.255", iprange.Fields{Country: "A"})}, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for n := 0; n < len(data); n++ {
+		if CheckFile(data[:n]) {
+			t.Fatalf("CheckFile accepted %d of %d bytes", n, len(data))
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package overlay
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// LabelOrder is the order of well known labels in the result text,
+// other labels follow in alphabetical order
+var LabelOrder = []string{"name", "site", "rack", "team", "vlan"}
+
+// Overlay is a user maintained database mapping CIDRs to labels,
+// it is consulted before the public databases.
+//
+// YAML files map CIDRs to labels, a plain string is the name label:
+//
+//	10.0.0.0/8:
+//	  site: 上海
+//	  team: infra
+//	10.1.2.0/24:
+//	  rack: A12
+//	  vlan: 120
+//	203.0.113.0/24: 办公网出口
+//
+// CSV files start with a header, the first column is the CIDR:
+//
+//	cidr,site,rack,team,vlan
+//	10.1.2.0/24,上海,A12,infra,120
+//
+// A match inherits the labels of the shorter prefixes containing it.
+type Overlay struct {
+	tree tree
+}
+
+type Result struct {
+	Prefix netip.Prefix      `json:"prefix"`
+	Labels map[string]string `json:"labels"`
+}
+
+func (r Result) String() string {
+	keys := make([]string, 0, len(r.Labels))
+	for k := range r.Labels {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := labelRank(keys[i]), labelRank(keys[j])
+		if a != b {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if v := r.Labels[k]; v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func labelRank(label string) int {
+	for i, l := range LabelOrder {
+		if l == label {
+			return i
+		}
+	}
+	return len(LabelOrder)
+}
+
+// NewOverlay loads a .csv file or a YAML file
+func NewOverlay(filePath string) (*Overlay, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Println("文件不存在，请创建自定义地址段文件并保存在", filePath)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	db := &Overlay{}
+	if strings.EqualFold(filepath.Ext(filePath), ".csv") {
+		err = db.readCSV(f)
+	} else {
+		err = db.readYAML(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return db, nil
+}
+
+func (db *Overlay) readYAML(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	for cidr, v := range entries {
+		labels := make(map[string]string)
+		switch v := v.(type) {
+		case nil:
+		case map[interface{}]interface{}:
+			for k, label := range v {
+				if label != nil {
+					labels[fmt.Sprint(k)] = fmt.Sprint(label)
+				}
+			}
+		case string:
+			labels["name"] = v
+		default:
+			return fmt.Errorf("%s 的标签格式错误", cidr)
+		}
+		if err := db.Add(cidr, labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *Overlay) readCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		labels := make(map[string]string)
+		for i := 1; i < len(row) && i < len(header); i++ {
+			if row[i] != "" {
+				labels[strings.TrimSpace(header[i])] = row[i]
+			}
+		}
+		if err := db.Add(row[0], labels); err != nil {
+			return err
+		}
+	}
+}
+
+// Add adds a CIDR or a single IP with its labels
+func (db *Overlay) Add(cidr string, labels map[string]string) error {
+	cidr = strings.TrimSpace(cidr)
+	var p netip.Prefix
+	if strings.Contains(cidr, "/") {
+		var err error
+		if p, err = netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("无效的 CIDR %q", cidr)
+		}
+	} else {
+		ip, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return fmt.Errorf("无效的 CIDR %q", cidr)
+		}
+		p = netip.PrefixFrom(ip, ip.BitLen())
+	}
+	if p.Addr().Is4In6() {
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	db.tree.insert(p, labels)
+	return nil
+}
+
+// Len returns the number of prefixes
+func (db *Overlay) Len() int {
+	return db.tree.size
+}
+
+// Lookup returns the longest prefix containing ip with the labels inherited from shorter ones
+func (db *Overlay) Lookup(ip netip.Addr) (Result, bool) {
+	matched := db.tree.lookup(ip.Unmap())
+	if len(matched) == 0 {
+		return Result{}, false
+	}
+	labels := make(map[string]string)
+	for _, n := range matched {
+		for k, v := range n.labels {
+			labels[k] = v
+		}
+	}
+	return Result{Prefix: matched[len(matched)-1].prefix, Labels: labels}, true
+}
+
+var ErrNotFound = errors.New("not found")
+
+func (db *Overlay) Find(query string, params ...string) (result fmt.Stringer, err error) {
+	ip, err := netip.ParseAddr(query)
+	if err != nil {
+		return nil, errors.New("query should be IP")
+	}
+	res, found := db.Lookup(ip.WithZone(""))
+	if !found {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+func (db *Overlay) Name() string {
+	return "overlay"
+}
@@ -0,0 +1,121 @@
+package overlay
+
+import (
+	"math/rand"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOverlayYAML(t *testing.T) {
+	db, err := NewOverlay(writeFile(t, "overlay.yml", `
+10.0.0.0/8:
+  site: 上海
+  team: infra
+10.1.2.0/24:
+  rack: A12
+  vlan: 120
+10.1.2.7: 跳板机
+203.0.113.0/24: 办公网出口
+2001:db8::/32:
+  site: 北京
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip, prefix, text string
+	}{
+		{"10.9.9.9", "10.0.0.0/8", "上海 infra"},
+		{"10.1.2.3", "10.1.2.0/24", "上海 A12 infra 120"},
+		{"10.1.2.7", "10.1.2.7/32", "跳板机 上海 A12 infra 120"},
+		{"::ffff:203.0.113.9", "203.0.113.0/24", "办公网出口"},
+		{"2001:db8:1::1", "2001:db8::/32", "北京"},
+	}
+	for _, tt := range tests {
+		res, err := db.Find(tt.ip)
+		if err != nil {
+			t.Errorf("Find(%s): %v", tt.ip, err)
+			continue
+		}
+		r := res.(Result)
+		if r.Prefix.String() != tt.prefix || r.String() != tt.text {
+			t.Errorf("Find(%s) = %s %q, want %s %q", tt.ip, r.Prefix, r, tt.prefix, tt.text)
+		}
+	}
+	for _, ip := range []string{"11.0.0.1", "192.168.1.1", "2001:db9::1"} {
+		if _, err := db.Find(ip); err != ErrNotFound {
+			t.Errorf("Find(%s) error = %v, want ErrNotFound", ip, err)
+		}
+	}
+}
+
+func TestOverlayCSV(t *testing.T) {
+	db, err := NewOverlay(writeFile(t, "overlay.csv", `cidr,site,rack,team,vlan
+# core network
+172.16.0.0/12,深圳,,net,
+172.16.5.0/24,深圳,B03,,305
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, found := db.Lookup(netip.MustParseAddr("172.16.5.1"))
+	if !found || res.String() != "深圳 B03 net 305" {
+		t.Errorf("Lookup = %q %v", res, found)
+	}
+
+	if _, err := NewOverlay(writeFile(t, "bad.csv", "cidr,site\n10.0.0.0/33,x\n")); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+}
+
+// TestTreeLongestPrefix compares the tree with a linear scan over random prefixes
+func TestTreeLongestPrefix(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randAddr := func(v4 bool) netip.Addr {
+		var b [16]byte
+		rnd.Read(b[:])
+		// keep addresses close together so prefixes nest
+		b[0], b[1] = 0x20, 0x01
+		if v4 {
+			return netip.AddrFrom4([4]byte{10, b[2] & 0x0f, b[3], b[4]})
+		}
+		return netip.AddrFrom16(b)
+	}
+
+	var db Overlay
+	var prefixes []netip.Prefix
+	for i := 0; i < 2000; i++ {
+		ip := randAddr(i%2 == 0)
+		p := netip.PrefixFrom(ip, 8+rnd.Intn(ip.BitLen()-7)).Masked()
+		prefixes = append(prefixes, p)
+		if err := db.Add(p.String(), map[string]string{"name": p.String()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 5000; i++ {
+		ip := randAddr(i%2 == 0)
+		var want netip.Prefix
+		for _, p := range prefixes {
+			if p.Contains(ip) && (!want.IsValid() || p.Bits() > want.Bits()) {
+				want = p
+			}
+		}
+		res, found := db.Lookup(ip)
+		if found != want.IsValid() || (found && res.Prefix != want) {
+			t.Fatalf("Lookup(%s) = %s %v, want %s", ip, res.Prefix, found, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package overlay
+
+import (
+	"net/netip"
+)
+
+// node is a node of a path compressed binary trie,
+// children share the first prefix.Bits() bits of prefix
+type node struct {
+	prefix netip.Prefix
+	labels map[string]string
+	set    bool
+	child  [2]*node
+}
+
+// tree is a radix tree of prefixes, IPv4 and IPv6 prefixes live in separate roots
+type tree struct {
+	roots [2]*node
+	size  int
+}
+
+func family(ip netip.Addr) int {
+	if ip.Is4() {
+		return 0
+	}
+	return 1
+}
+
+// bit returns the i-th bit of ip, counting from the most significant one
+func bit(ip netip.Addr, i int) int {
+	b := ip.As16()
+	if ip.Is4() {
+		i += 96
+	}
+	return int(b[i/8]>>(7-i%8)) & 1
+}
+
+// commonBits returns the length of the common prefix of a and b
+func commonBits(a, b netip.Prefix) int {
+	n := a.Bits()
+	if b.Bits() < n {
+		n = b.Bits()
+	}
+	for i := 0; i < n; i++ {
+		if bit(a.Addr(), i) != bit(b.Addr(), i) {
+			return i
+		}
+	}
+	return n
+}
+
+// insert adds a prefix, labels of a prefix inserted twice are merged
+func (t *tree) insert(p netip.Prefix, labels map[string]string) {
+	p = p.Masked()
+	n := &t.roots[family(p.Addr())]
+	for {
+		cur := *n
+		if cur == nil {
+			*n = &node{prefix: p, labels: labels, set: true}
+			t.size++
+			return
+		}
+
+		common := commonBits(cur.prefix, p)
+		if common == cur.prefix.Bits() {
+			if common == p.Bits() {
+				if !cur.set {
+					cur.labels, cur.set = make(map[string]string), true
+					t.size++
+				}
+				for k, v := range labels {
+					cur.labels[k] = v
+				}
+				return
+			}
+			n = &cur.child[bit(p.Addr(), common)]
+			continue
+		}
+
+		// split at the first differing bit
+		mid := &node{prefix: netip.PrefixFrom(p.Addr(), common).Masked()}
+		mid.child[bit(cur.prefix.Addr(), common)] = cur
+		if common == p.Bits() {
+			mid.labels, mid.set = labels, true
+		} else {
+			mid.child[bit(p.Addr(), common)] = &node{prefix: p, labels: labels, set: true}
+		}
+		*n = mid
+		t.size++
+		return
+	}
+}
+
+// lookup returns the prefixes containing ip, from the shortest to the longest
+func (t *tree) lookup(ip netip.Addr) []*node {
+	var matched []*node
+	n := t.roots[family(ip)]
+	for n != nil && n.prefix.Contains(ip) {
+		if n.set {
+			matched = append(matched, n)
+		}
+		if n.prefix.Bits() == ip.BitLen() {
+			break
+		}
+		n = n.child[bit(ip, n.prefix.Bits())]
+	}
+	return matched
+}
@@ -0,0 +1,301 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	barWidth       = 24
+	renderInterval = time.Millisecond * 200
+)
+
+type State int
+
+const (
+	StatePending State = iota
+	StateRunning
+	StateDone
+	StateFailed
+	StateSkipped
+)
+
+// Board renders one progress bar per task when out is a terminal,
+// and falls back to plain log lines otherwise
+type Board struct {
+	out io.Writer
+	tty bool
+
+	mu    sync.Mutex
+	tasks []*Task
+	lines int // lines drawn by the last render
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewBoard(out *os.File) *Board {
+	return &Board{
+		out: out,
+		tty: isatty.IsTerminal(out.Fd()) || isatty.IsCygwinTerminal(out.Fd()),
+	}
+}
+
+func (b *Board) IsTTY() bool {
+	return b.tty
+}
+
+func (b *Board) Add(name string) *Task {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := &Task{Name: name, board: b}
+	b.tasks = append(b.tasks, t)
+	return t
+}
+
+// Start begins rendering, log output is redirected above the bars until Stop is called
+func (b *Board) Start() {
+	if !b.tty {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	log.SetOutput(b)
+
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(renderInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.mu.Lock()
+				b.render()
+				b.mu.Unlock()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (b *Board) Stop() {
+	if !b.tty || b.stop == nil {
+		return
+	}
+	close(b.stop)
+	<-b.done
+	log.SetOutput(os.Stderr)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.render()
+}
+
+// Write prints p above the progress bars, so Board can be used as log output
+func (b *Board) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.clear()
+	n, err := b.out.Write(p)
+	b.render()
+	return n, err
+}
+
+func (b *Board) clear() {
+	if b.lines > 0 {
+		_, _ = fmt.Fprintf(b.out, "\x1b[%dA\x1b[J", b.lines)
+		b.lines = 0
+	}
+}
+
+func (b *Board) render() {
+	var s strings.Builder
+	if b.lines > 0 {
+		s.WriteString(fmt.Sprintf("\x1b[%dA", b.lines))
+	}
+	for _, t := range b.tasks {
+		s.WriteString("\r\x1b[2K")
+		s.WriteString(t.line())
+		s.WriteString("\n")
+	}
+	_, _ = io.WriteString(b.out, s.String())
+	b.lines = len(b.tasks)
+}
+
+// Task is the progress of a single download
+type Task struct {
+	Name  string
+	board *Board
+
+	mu      sync.Mutex
+	state   State
+	current int64
+	total   int64
+	start   time.Time
+	end     time.Time
+	err     error
+	logged  int64 // last quarter reported in plain mode
+}
+
+func (t *Task) Begin() {
+	t.mu.Lock()
+	t.state = StateRunning
+	t.start = time.Now()
+	t.total = -1
+	t.mu.Unlock()
+
+	if !t.board.tty {
+		log.Printf("%s: 开始更新\n", t.Name)
+	}
+}
+
+// Update records the downloaded size, it matches common.ProgressFunc
+func (t *Task) Update(current, total int64) {
+	t.mu.Lock()
+	t.current, t.total = current, total
+	quarter := int64(0)
+	if total > 0 {
+		quarter = current * 4 / total
+	}
+	report := !t.board.tty && quarter > t.logged && quarter < 4
+	if report {
+		t.logged = quarter
+	}
+	t.mu.Unlock()
+
+	if report {
+		log.Printf("%s: 已下载 %d%% (%s/%s)\n", t.Name, quarter*25, FormatBytes(current), FormatBytes(total))
+	}
+}
+
+func (t *Task) Finish(err error) {
+	t.mu.Lock()
+	t.end = time.Now()
+	t.err = err
+	if err != nil {
+		t.state = StateFailed
+	} else {
+		t.state = StateDone
+	}
+	t.mu.Unlock()
+
+	if !t.board.tty {
+		if err != nil {
+			log.Printf("%s: 更新失败: %v\n", t.Name, err)
+		} else {
+			log.Printf("%s: 更新完成，用时 %s\n", t.Name, t.Elapsed())
+		}
+	}
+}
+
+// Skip marks the task as not applicable, reason is kept as its error
+func (t *Task) Skip(reason error) {
+	t.mu.Lock()
+	t.end = time.Now()
+	t.err = reason
+	t.state = StateSkipped
+	t.mu.Unlock()
+}
+
+func (t *Task) State() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+func (t *Task) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Size returns the downloaded bytes
+func (t *Task) Size() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
+
+func (t *Task) Elapsed() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.elapsed()
+}
+
+func (t *Task) elapsed() time.Duration {
+	if t.start.IsZero() {
+		return 0
+	}
+	if t.end.IsZero() {
+		return time.Since(t.start).Round(time.Millisecond * 100)
+	}
+	return t.end.Sub(t.start).Round(time.Millisecond * 100)
+}
+
+func (t *Task) line() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	name := fmt.Sprintf("%-12s", t.Name)
+	switch t.state {
+	case StatePending:
+		return name + " 等待中"
+	case StateDone:
+		return fmt.Sprintf("%s [%s] 完成 %s 用时 %s", name, strings.Repeat("=", barWidth), FormatBytes(t.current), t.elapsed())
+	case StateFailed:
+		return fmt.Sprintf("%s 失败: %v", name, t.err)
+	case StateSkipped:
+		return fmt.Sprintf("%s 跳过: %v", name, t.err)
+	}
+
+	elapsed := time.Since(t.start).Seconds()
+	rate := float64(0)
+	if elapsed > 0 {
+		rate = float64(t.current) / elapsed
+	}
+
+	if t.total <= 0 {
+		return fmt.Sprintf("%s [%s] %s %s/s", name, strings.Repeat("?", barWidth), FormatBytes(t.current), FormatBytes(int64(rate)))
+	}
+
+	ratio := float64(t.current) / float64(t.total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * barWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+
+	eta := "--"
+	if rate > 0 {
+		eta = time.Duration(float64(t.total-t.current) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s [%s] %5.1f%% %s/%s %s/s ETA %s", name, bar, ratio*100,
+		FormatBytes(t.current), FormatBytes(t.total), FormatBytes(int64(rate)), eta)
+}
+
+// FormatBytes formats n in a human readable way, like 1.5MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,108 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:                  "0B",
+		1023:               "1023B",
+		1024:               "1.0KiB",
+		1536:               "1.5KiB",
+		5 * 1024 * 1024:    "5.0MiB",
+		3 << 30:            "3.0GiB",
+		1<<40 + 1<<39:      "1.5TiB",
+		1024*1024 - 1:      "1024.0KiB",
+		70 * 1024 * 1024:   "70.0MiB",
+		1024 * 1024 * 1024: "1.0GiB",
+	} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestTaskStates(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	b := &Board{out: &bytes.Buffer{}}
+	done, failed, skipped := b.Add("qqwry"), b.Add("geoip"), b.Add("cdn")
+	for _, task := range []*Task{done, failed, skipped} {
+		if task.State() != StatePending {
+			t.Fatalf("%s: state = %d, want pending", task.Name, task.State())
+		}
+	}
+
+	done.Begin()
+	if done.State() != StateRunning {
+		t.Errorf("state after Begin = %d, want running", done.State())
+	}
+	for _, current := range []int64{10, 30, 60, 100} {
+		done.Update(current, 100)
+	}
+	done.Finish(nil)
+	if done.State() != StateDone || done.Err() != nil || done.Size() != 100 {
+		t.Errorf("finished task: state %d, err %v, size %d", done.State(), done.Err(), done.Size())
+	}
+
+	errDownload := errors.New("connection reset")
+	failed.Begin()
+	failed.Finish(errDownload)
+	if failed.State() != StateFailed || failed.Err() != errDownload {
+		t.Errorf("failed task: state %d, err %v", failed.State(), failed.Err())
+	}
+
+	errUnsupported := errors.New("unsupported")
+	skipped.Skip(errUnsupported)
+	if skipped.State() != StateSkipped || skipped.Err() != errUnsupported {
+		t.Errorf("skipped task: state %d, err %v", skipped.State(), skipped.Err())
+	}
+
+	// without a terminal the progress is logged once per quarter, not at 100%
+	out := logs.String()
+	for _, want := range []string{"qqwry: 开始更新", "已下载 25%", "已下载 50%", "qqwry: 更新完成", "geoip: 更新失败: connection reset"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "已下载") != 2 {
+		t.Errorf("log should report two quarters:\n%s", out)
+	}
+}
+
+func TestBoardRender(t *testing.T) {
+	var out bytes.Buffer
+	b := &Board{out: &out, tty: true}
+	pending, running, done := b.Add("pending"), b.Add("running"), b.Add("done")
+	running.Begin()
+	running.Update(512, 1024)
+	done.Begin()
+	done.Update(2048, 2048)
+	done.Finish(nil)
+
+	_, _ = b.Write([]byte("message\n"))
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 || lines[0] != "message" {
+		t.Fatalf("render = %q", out.String())
+	}
+	for i, want := range []string{pending.Name + "      等待中", " 50.0% 512B/1.0KiB", "] 完成 2.0KiB"} {
+		if !strings.Contains(lines[i+1], want) {
+			t.Errorf("line %d = %q, want it to contain %q", i+1, lines[i+1], want)
+		}
+	}
+
+	// the next write moves up over the bars drawn before
+	out.Reset()
+	_, _ = b.Write([]byte("again\n"))
+	if !strings.HasPrefix(out.String(), "\x1b[3A\x1b[J") {
+		t.Errorf("second write starts with %q", out.String()[:8])
+	}
+}
@@ -0,0 +1,58 @@
+package psl
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrPunycode is returned for labels that are not valid punycode
+var ErrPunycode = errors.New("invalid punycode")
+
+const acePrefix = "xn--"
+
+// ToASCII converts the Unicode labels of domain to their xn-- form with the
+// UTS #46 lookup mapping, so full-width letters and the ideographic full stop
+// are accepted. ASCII names are only lowercased: hosts like r3---sn-x.googlevideo.com
+// or _dmarc labels are not valid IDNA but common in logs
+func ToASCII(domain string) (string, error) {
+	if isASCII(domain) {
+		return strings.ToLower(domain), nil
+	}
+	return idna.Lookup.ToASCII(domain)
+}
+
+// ToUnicode converts the xn-- labels of domain to Unicode, an error is returned
+// if one of them is not a valid IDNA label or decodes to plain ASCII
+func ToUnicode(domain string) (string, error) {
+	domain = strings.ToLower(domain)
+	if !strings.Contains(domain, acePrefix) {
+		return domain, nil
+	}
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		if !strings.HasPrefix(label, acePrefix) {
+			continue
+		}
+		dec, err := idna.Lookup.ToUnicode(label)
+		if err != nil {
+			return "", err
+		}
+		if isASCII(dec) {
+			return "", ErrPunycode
+		}
+		labels[i] = dec
+	}
+	return strings.Join(labels, "."), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
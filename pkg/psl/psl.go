@@ -0,0 +1,178 @@
+// Package psl looks up public suffixes and registrable domains with the rules of
+// the Public Suffix List, https://publicsuffix.org/list/.
+// A snapshot of the list is embedded, a newer copy can be loaded with NewList.
+package psl
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var DownloadUrls = []string{
+	"https://publicsuffix.org/list/public_suffix_list.dat",
+	"https://cdn.jsdelivr.net/gh/publicsuffix/list/public_suffix_list.dat",
+	"https://raw.githubusercontent.com/publicsuffix/list/master/public_suffix_list.dat",
+}
+
+//go:embed public_suffix_list.dat
+var embedded []byte
+
+// ErrNotFound is returned by Find for names without a known public suffix
+var ErrNotFound = errors.New("public suffix not found")
+
+const (
+	ruleNormal = 1 << iota
+	// ruleWildcard is set on the parent of *.parent rules
+	ruleWildcard
+	ruleException
+)
+
+// List is a parsed Public Suffix List, rules are stored in their xn-- form
+type List struct {
+	rules map[string]uint8
+}
+
+var (
+	defaultList     *List
+	defaultListOnce sync.Once
+)
+
+// Default returns the embedded list
+func Default() *List {
+	defaultListOnce.Do(func() {
+		l, err := Parse(bytes.NewReader(embedded))
+		if err != nil {
+			panic(err)
+		}
+		defaultList = l
+	})
+	return defaultList
+}
+
+// NewList reads the list at filePath
+func NewList(filePath string) (*List, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a list in the public_suffix_list.dat format
+func Parse(r io.Reader) (*List, error) {
+	l := &List{rules: make(map[string]uint8)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "//") {
+			continue
+		}
+		rule := fields[0]
+		kind := uint8(ruleNormal)
+		switch {
+		case strings.HasPrefix(rule, "!"):
+			rule, kind = rule[1:], ruleException
+		case strings.HasPrefix(rule, "*."):
+			rule, kind = rule[2:], ruleWildcard
+		}
+		ascii, err := ToASCII(rule)
+		if err != nil || ascii == "" || strings.Contains(ascii, "*") {
+			return nil, fmt.Errorf("line %d: invalid rule %q", line, fields[0])
+		}
+		l.rules[ascii] |= kind
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(l.rules) == 0 {
+		return nil, errors.New("empty public suffix list")
+	}
+	return l, nil
+}
+
+// CheckFile reports whether data is a valid list
+func CheckFile(data []byte) bool {
+	if !bytes.Contains(data, []byte("===BEGIN ICANN DOMAINS===")) {
+		return false
+	}
+	_, err := Parse(bytes.NewReader(data))
+	return err == nil
+}
+
+// Len returns the number of rules
+func (l *List) Len() int {
+	return len(l.rules)
+}
+
+// PublicSuffix returns the public suffix of an xn-- form lowercase domain,
+// known is false if no rule matched and the suffix is the top level label
+func (l *List) PublicSuffix(domain string) (suffix string, known bool) {
+	domain = strings.TrimSuffix(domain, ".")
+	suffix = domain[strings.LastIndexByte(domain, '.')+1:]
+
+	parent := ""
+	for i := len(domain); i > 0; {
+		i = strings.LastIndexByte(domain[:i], '.') + 1
+		name := domain[i:]
+		if l.rules[name]&ruleException != 0 {
+			return parent, true
+		}
+		if l.rules[name]&ruleNormal != 0 || parent != "" && l.rules[parent]&ruleWildcard != 0 {
+			suffix, known = name, true
+		}
+		parent = name
+		i--
+	}
+	return
+}
+
+// Registrable returns the public suffix of domain plus one label,
+// it is empty if domain is a public suffix itself or has no known public suffix
+func (l *List) Registrable(domain string) string {
+	domain = strings.TrimSuffix(domain, ".")
+	suffix, known := l.PublicSuffix(domain)
+	if !known || len(suffix) >= len(domain) {
+		return ""
+	}
+	i := strings.LastIndexByte(domain[:len(domain)-len(suffix)-1], '.')
+	return domain[i+1:]
+}
+
+// Result is the public suffix and the registrable domain of a name
+type Result struct {
+	Suffix      string `json:"suffix"`
+	Registrable string `json:"registrable"`
+}
+
+func (r Result) String() string {
+	if r.Registrable == "" {
+		return "公共后缀 " + r.Suffix
+	}
+	return r.Registrable
+}
+
+// Find looks up the public suffix and the registrable domain of query,
+// Unicode names are converted to their xn-- form first
+func (l *List) Find(query string, params ...string) (result fmt.Stringer, err error) {
+	domain, err := ToASCII(query)
+	if err != nil {
+		return nil, err
+	}
+	suffix, known := l.PublicSuffix(domain)
+	if !known {
+		return nil, ErrNotFound
+	}
+	return Result{Suffix: suffix, Registrable: l.Registrable(domain)}, nil
+}
+
+func (l *List) Name() string {
+	return "psl"
+}
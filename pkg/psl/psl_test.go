@@ -0,0 +1,136 @@
+package psl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPunycode(t *testing.T) {
+	tests := []struct {
+		unicode, ascii string
+	}{
+		{"bücher.de", "xn--bcher-kva.de"},
+		{"例子.中国", "xn--fsqu00a.xn--fiqs8s"},
+		{"München.example", "xn--mnchen-3ya.example"},
+		{"пример.рф", "xn--e1afmkfd.xn--p1ai"},
+		{"www.example.com", "www.example.com"},
+	}
+	for _, tt := range tests {
+		ascii, err := ToASCII(tt.unicode)
+		if err != nil || ascii != tt.ascii {
+			t.Errorf("ToASCII(%q) = %q, %v, want %q", tt.unicode, ascii, err, tt.ascii)
+		}
+		unicode, err := ToUnicode(tt.ascii)
+		if err != nil || unicode != strings.ToLower(tt.unicode) {
+			t.Errorf("ToUnicode(%q) = %q, %v, want %q", tt.ascii, unicode, err, tt.unicode)
+		}
+	}
+
+	// UTS #46 maps full-width characters and the ideographic full stop
+	for s, want := range map[string]string{
+		"例子。中国":       "xn--fsqu00a.xn--fiqs8s",
+		"例子．中国":       "xn--fsqu00a.xn--fiqs8s",
+		"ＷＷＷ．例子．中国":   "www.xn--fsqu00a.xn--fiqs8s",
+		"BÜCHER.de":   "xn--bcher-kva.de",
+		"r3---sn.com": "r3---sn.com",
+	} {
+		if ascii, err := ToASCII(s); err != nil || ascii != want {
+			t.Errorf("ToASCII(%q) = %q, %v, want %q", s, ascii, err, want)
+		}
+	}
+
+	for _, s := range []string{"xn--.com", "xn--abc.com", "xn--999999999.com", "xn--ü.com"} {
+		if got, err := ToUnicode(s); err == nil {
+			t.Errorf("ToUnicode(%q) = %q, want error", s, got)
+		}
+	}
+}
+
+func TestPublicSuffix(t *testing.T) {
+	l, err := Parse(strings.NewReader(`// ===BEGIN ICANN DOMAINS===
+com
+uk
+co.uk
+jp
+*.kawasaki.jp
+!city.kawasaki.jp
+*.ck
+!www.ck
+中国
+// ===BEGIN PRIVATE DOMAINS===
+github.io
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		domain, suffix string
+		known          bool
+		registrable    string
+	}{
+		{"www.example.com", "com", true, "example.com"},
+		{"com", "com", true, ""},
+		{"a.b.example.co.uk", "co.uk", true, "example.co.uk"},
+		{"example.uk", "uk", true, "example.uk"},
+		{"user.github.io", "github.io", true, "user.github.io"},
+		{"kawasaki.jp", "jp", true, "kawasaki.jp"},
+		{"b.kawasaki.jp", "b.kawasaki.jp", true, ""},
+		{"a.b.kawasaki.jp", "b.kawasaki.jp", true, "a.b.kawasaki.jp"},
+		{"www.city.kawasaki.jp", "kawasaki.jp", true, "city.kawasaki.jp"},
+		{"www.ck", "ck", true, "www.ck"},
+		{"a.www.ck", "ck", true, "www.ck"},
+		{"a.b.ck", "b.ck", true, "a.b.ck"},
+		{"xn--fsqu00a.xn--fiqs8s", "xn--fiqs8s", true, "xn--fsqu00a.xn--fiqs8s"},
+		{"main.go", "go", false, ""},
+		{"example.com.", "com", true, "example.com"},
+	}
+	for _, tt := range tests {
+		suffix, known := l.PublicSuffix(tt.domain)
+		if suffix != tt.suffix || known != tt.known {
+			t.Errorf("PublicSuffix(%q) = %q, %v, want %q, %v", tt.domain, suffix, known, tt.suffix, tt.known)
+		}
+		if got := l.Registrable(tt.domain); got != tt.registrable {
+			t.Errorf("Registrable(%q) = %q, want %q", tt.domain, got, tt.registrable)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	l := Default()
+	if !CheckFile(embedded) || l.Len() < 1000 {
+		t.Fatalf("embedded list has %d rules", l.Len())
+	}
+
+	res, err := l.Find("www.例子.中国")
+	if err != nil || res.(Result).Registrable != "xn--fsqu00a.xn--fiqs8s" {
+		t.Errorf("Find = %v, %v", res, err)
+	}
+	for _, s := range []string{"main.go", "java.util.list", "file.tar.gz"} {
+		if res, err := l.Find(s); err == nil {
+			t.Errorf("Find(%q) = %v, want error", s, res)
+		}
+	}
+	if CheckFile([]byte("com\nnet\n")) {
+		t.Error("CheckFile accepted a list without the ICANN section")
+	}
+}
+
+func FuzzPunycode(f *testing.F) {
+	for _, s := range []string{"bcher-kva", "fsqu00a", "", "-", "999999999", "a-b-c"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		dec, err := ToUnicode(acePrefix + s)
+		if err != nil {
+			return
+		}
+		enc, err := ToASCII(dec)
+		if err != nil {
+			t.Fatalf("ToASCII(%q) failed: %v", dec, err)
+		}
+		if again, err := ToUnicode(enc); err != nil || again != dec {
+			t.Fatalf("round trip of %q: %q -> %q, %v", s, enc, again, err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+// Package pty opens pseudo terminals, so that commands run by nabili exec
+// keep their line buffering and colors as if they wrote to a terminal.
+// Only Linux is supported, Open returns ErrUnsupported elsewhere and
+// commands are run with pipes instead.
+package pty
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// ErrUnsupported is returned by Open on platforms without pseudo terminal support
+var ErrUnsupported = errors.New("当前平台不支持伪终端")
+
+// Start starts c with its stdout and stderr attached to a pseudo terminal each,
+// the returned files are the master sides to read the output from.
+// The stdout terminal becomes the controlling terminal of c in a new session,
+// so signals from the user's terminal reach nabili only and are forwarded.
+func Start(c *exec.Cmd) (stdout, stderr *os.File, err error) {
+	outMaster, outSlave, err := Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer outSlave.Close()
+	errMaster, errSlave, err := Open()
+	if err != nil {
+		outMaster.Close()
+		return nil, nil, err
+	}
+	defer errSlave.Close()
+
+	InheritSize(os.Stdout, outMaster)
+	InheritSize(os.Stderr, errMaster)
+	c.Stdout, c.Stderr = outSlave, errSlave
+	c.SysProcAttr = sysProcAttr()
+	if err := c.Start(); err != nil {
+		outMaster.Close()
+		errMaster.Close()
+		return nil, nil, err
+	}
+	return outMaster, errMaster, nil
+}
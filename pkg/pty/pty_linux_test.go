@@ -0,0 +1,36 @@
+package pty
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	c := exec.Command("sh", "-c", `[ -t 1 ] && echo tty; [ -t 2 ] && echo tty-err >&2; exit 3`)
+	stdout, stderr, err := Start(c)
+	if err != nil {
+		t.Skip("no pseudo terminal:", err)
+	}
+
+	read := func(r io.Reader) string {
+		var buf bytes.Buffer
+		_, err := io.Copy(&buf, r)
+		if err != nil && !IsEOF(err) {
+			t.Error(err)
+		}
+		return buf.String()
+	}
+	errc := make(chan string)
+	go func() { errc <- read(stderr) }()
+	if got := read(stdout); got != "tty\r\n" {
+		t.Errorf("stdout = %q", got)
+	}
+	if got := <-errc; got != "tty-err\r\n" {
+		t.Errorf("stderr = %q", got)
+	}
+	if err := c.Wait(); err == nil || c.ProcessState.ExitCode() != 3 {
+		t.Errorf("Wait = %v, exit code %d", err, c.ProcessState.ExitCode())
+	}
+}
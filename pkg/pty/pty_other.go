@@ -0,0 +1,31 @@
+//go:build !linux
+
+package pty
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals are forwarded to the command, there is no ResizeSignal
+var (
+	Signals      = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	ResizeSignal os.Signal
+)
+
+// Open returns ErrUnsupported
+func Open() (master, slave *os.File, err error) {
+	return nil, nil, ErrUnsupported
+}
+
+// InheritSize does nothing
+func InheritSize(from, to *os.File) {}
+
+// IsEOF reports false, reads from pipes end with io.EOF
+func IsEOF(err error) bool {
+	return false
+}
+
+func sysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
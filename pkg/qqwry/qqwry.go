@@ -7,12 +7,14 @@ import (
 	"io"
 	"log"
 	"net"
+	"net/netip"
 	"os"
 
 	"path/filepath"
 
 	"github.com/abc1763613206/nabili/internal/constant"
 	"github.com/abc1763613206/nabili/pkg/download"
+	"github.com/abc1763613206/nabili/pkg/iprange"
 	"github.com/abc1763613206/nabili/pkg/wry"
 )
 
@@ -61,7 +63,15 @@ func NewQQwry(filePath string) (*QQwry, error) {
 	}
 
 	if !CheckFile(fileData) {
-		log.Fatalln("纯真 IP 库存在错误，请重新下载")
+		return nil, errors.New("纯真 IP 库存在错误，请重新下载")
+	}
+	return FromBytes(fileData)
+}
+
+// FromBytes opens a qqwry database from its content
+func FromBytes(fileData []byte) (*QQwry, error) {
+	if !CheckFile(fileData) {
+		return nil, errors.New("纯真 IP 库存在错误")
 	}
 
 	header := fileData[0:8]
@@ -98,7 +108,9 @@ func (db QQwry) Find(query string, params ...string) (result fmt.Stringer, err e
 	}
 
 	reader := wry.NewReader(db.Data)
-	reader.Parse(offset + 4)
+	if err := reader.Parse(offset + 4); err != nil {
+		return nil, err
+	}
 	return reader.Result.DecodeGBK(), nil
 }
 
@@ -115,9 +127,30 @@ func CheckFile(data []byte) bool {
 	start := binary.LittleEndian.Uint32(header[:4])
 	end := binary.LittleEndian.Uint32(header[4:])
 
-	if start >= end || uint32(len(data)) < end+7 {
+	if start < 8 || start > end || (end-start)%7 != 0 || uint64(len(data)) < uint64(end)+7 {
 		return false
 	}
 
 	return true
 }
+
+// Ranges walks every record of the index
+func (db QQwry) Ranges(fn func(iprange.Range) error) error {
+	it := db.IterV4(netip.Addr{}, netip.Addr{})
+	for it.Next() {
+		res := it.Result()
+		res.DecodeGBK().Trim()
+		err := fn(iprange.Range{
+			Start:  it.Start(),
+			End:    it.End(),
+			Fields: iprange.ParseLocation(res.Country, res.Area),
+		})
+		if err == iprange.ErrStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
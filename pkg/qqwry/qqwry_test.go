@@ -0,0 +1,82 @@
+package qqwry
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+	"github.com/abc1763613206/nabili/pkg/wry"
+)
+
+func TestWriteQQwryRoundTrip(t *testing.T) {
+	records := []wry.Record{
+		{Start: netip.MustParseAddr("0.0.0.0"), End: netip.MustParseAddr("1.0.0.255"), Country: "IANA", Area: "保留地址"},
+		{Start: netip.MustParseAddr("1.0.1.0"), End: netip.MustParseAddr("1.0.3.255"), Country: "中国–福建–福州", Area: "电信"},
+		{Start: netip.MustParseAddr("1.0.4.0"), End: netip.MustParseAddr("255.255.255.255"), Country: "中国–福建–福州", Area: "电信"},
+	}
+	var buf bytes.Buffer
+	if err := wry.WriteQQwry(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckFile(buf.Bytes()) {
+		t.Fatal("CheckFile rejected a written database")
+	}
+
+	path := filepath.Join(t.TempDir(), "qqwry.dat")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewQQwry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ ip, want string }{
+		{"1.0.0.1", "IANA 保留地址"},
+		{"1.0.2.3", "中国–福建–福州 电信"},
+		{"8.8.8.8", "中国–福建–福州 电信"},
+	} {
+		res, err := db.Find(tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.String() != tt.want {
+			t.Errorf("Find(%s) = %q, want %q", tt.ip, res, tt.want)
+		}
+	}
+
+	// a single record database
+	buf.Reset()
+	if err := wry.WriteQQwry(&buf, records[2:]); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckFile(buf.Bytes()) {
+		t.Fatal("CheckFile rejected a single record database")
+	}
+}
+
+func FuzzCheckFile(f *testing.F) {
+	var buf bytes.Buffer
+	_ = wry.WriteQQwry(&buf, []wry.Record{
+		{Start: netip.MustParseAddr("0.0.0.0"), End: netip.MustParseAddr("1.0.0.255"), Country: "IANA"},
+		{Start: netip.MustParseAddr("1.0.1.0"), End: netip.MustParseAddr("255.255.255.255"), Country: "中国", Area: "电信"},
+	})
+	f.Add(buf.Bytes())
+	// the index end overflowed uint32 in the length check
+	f.Add([]byte{8, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if !CheckFile(data) {
+			return
+		}
+		db, err := FromBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ip := range []string{"0.0.0.0", "1.0.0.1", "8.8.8.8", "255.255.255.255"} {
+			_, _ = db.Find(ip)
+		}
+		_ = db.Ranges(func(iprange.Range) error { return nil })
+	})
+}
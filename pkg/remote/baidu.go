@@ -44,6 +44,9 @@ func (b *BaiduSource) Find(query string, params ...string) (result fmt.Stringer,
 	if ip == nil {
 		return nil, errors.New("invalid IP address")
 	}
+	if err := checkQueryable(ip); err != nil {
+		return nil, err
+	}
 
 	url := fmt.Sprintf("%s?ip=%s", BaiduAPIURL, query)
 	client := common.GetHttpClient()
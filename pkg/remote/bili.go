@@ -43,6 +43,9 @@ func (b *BiliSource) Find(query string, params ...string) (result fmt.Stringer,
 	if ip == nil {
 		return nil, errors.New("invalid IP address")
 	}
+	if err := checkQueryable(ip); err != nil {
+		return nil, err
+	}
 
 	url := fmt.Sprintf("%s?ip=%s", BiliAPIURL, query)
 	client := common.GetHttpClient()
@@ -42,6 +42,9 @@ func (i *IqiyiSource) Find(query string, params ...string) (result fmt.Stringer,
 	if ip == nil {
 		return nil, errors.New("invalid IP address")
 	}
+	if err := checkQueryable(ip); err != nil {
+		return nil, err
+	}
 
 	url := fmt.Sprintf("%s?version=1.1.1&ip=%s", IqiyiAPIURL, query)
 	client := common.GetHttpClient()
@@ -2,8 +2,11 @@ package remote
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
 	"sync"
 
+	"github.com/abc1763613206/nabili/pkg/bogon"
 	"github.com/abc1763613206/nabili/pkg/dbif"
 )
 
@@ -53,4 +56,13 @@ type RemoteResult struct {
 
 func (r *RemoteResult) String() string {
 	return r.Result.String()
-}
\ No newline at end of file
+}
+
+// checkQueryable rejects special-purpose addresses, they are never sent to remote APIs
+func checkQueryable(ip net.IP) error {
+	addr, _ := netip.AddrFromSlice(ip)
+	if class, found := bogon.Classify(addr.Unmap()); found {
+		return fmt.Errorf("%s 属于 %s (%s)，不会发送到远程接口", ip, class.Prefix, class.Name)
+	}
+	return nil
+}
@@ -0,0 +1,372 @@
+package trace
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// mtrColumns are the statistics columns of mtr --report without -o
+var mtrColumns = []string{"Loss%", "Snt", "Last", "Avg", "Best", "Wrst", "StDev"}
+
+var errNotEnclosed = errors.New("not an address in parentheses or brackets")
+
+// Parser reads a trace line by line, lines it does not recognize are ignored
+type Parser struct {
+	Trace
+
+	cur     *Hop
+	columns []string
+}
+
+// Parse parses a line and returns the hop finished by it, a hop is finished when
+// the line of a later hop is read, as replies of a hop may span several lines
+func (p *Parser) Parse(line string) *Hop {
+	line = strings.TrimRight(line, "\r\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(fields[0], "traceroute") && len(fields) > 2 && fields[1] == "to":
+		p.Format = FormatTraceroute
+		p.Target = strings.TrimSuffix(fields[2], ",")
+	case strings.EqualFold(fields[0], "Tracing") && len(fields) > 3:
+		p.Format = FormatTracert
+		p.Target = fields[3]
+	case fields[0] == "HOST:" && len(fields) > 2:
+		p.Format = FormatMTR
+		p.columns = fields[2:]
+	case strings.HasSuffix(fields[0], ".|--"):
+		ttl, err := strconv.Atoi(strings.TrimSuffix(fields[0], ".|--"))
+		if err != nil {
+			return nil
+		}
+		p.Format = FormatMTR
+		finished := p.next(ttl)
+		p.parseMTR(fields[1:])
+		return finished
+	case strings.HasSuffix(fields[0], ":") || strings.HasSuffix(fields[0], "?:"):
+		ttl, err := strconv.Atoi(strings.TrimRight(fields[0], "?:"))
+		if err != nil {
+			return nil
+		}
+		// the first line of tracepath is the path MTU of the local host
+		if len(fields) > 1 && fields[1] == "[LOCALHOST]" {
+			return nil
+		}
+		p.Format = FormatTracepath
+		finished := p.next(ttl)
+		p.parseTracepath(fields[1:])
+		return finished
+	default:
+		if ttl, err := strconv.Atoi(fields[0]); err == nil && ttl > 0 {
+			if p.Format == "" {
+				p.Format = FormatTraceroute
+			}
+			finished := p.next(ttl)
+			p.parseTraceroute(fields[1:])
+			return finished
+		}
+		// further replies of the hop on their own lines, by BSD traceroute or mtr like
+		//	    |  `|-- 10.0.0.2
+		if p.cur != nil && line[0] == ' ' {
+			if i := indexOf(fields, "|--"); i >= 0 && p.Format == FormatMTR {
+				if ip, err := netip.ParseAddr(lastOf(fields[i+1:])); err == nil {
+					p.cur.reply(ip, "")
+				}
+			} else if p.Format == FormatTraceroute {
+				p.parseTraceroute(fields)
+			}
+		}
+	}
+	return nil
+}
+
+// Flush returns the last hop once the trace is finished
+func (p *Parser) Flush() *Hop {
+	h := p.cur
+	p.cur = nil
+	if h != nil {
+		h.finish()
+	}
+	return h
+}
+
+// next starts the hop of ttl and returns the previous one, lines of the same ttl are merged
+func (p *Parser) next(ttl int) *Hop {
+	if p.cur != nil && p.cur.TTL == ttl {
+		return nil
+	}
+	finished := p.Flush()
+	p.cur = &Hop{TTL: ttl, Replies: []*Reply{}}
+	return finished
+}
+
+// parseTraceroute parses the fields after the ttl of traceroute and tracert, like
+//
+//	gw (10.0.0.1)  0.3 ms  0.2 ms 10.0.0.2 (10.0.0.2) [AS64512]  0.4 ms !H
+//	<1 ms    2 ms     *     gw [10.0.0.1]
+//
+// Host names only count if they are followed by the address, so messages like
+// Request timed out are skipped.
+func (p *Parser) parseTraceroute(fields []string) {
+	h := p.cur
+	var cur *Reply
+	var leading []float64 // tracert prints the times before the reply
+	var asn uint
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		next := ""
+		if i+1 < len(fields) {
+			next = fields[i+1]
+		}
+
+		if f == "*" {
+			h.lost++
+			continue
+		}
+		if ms, n := parseRTT(f, next); n > 0 {
+			i += n - 1
+			if cur == nil {
+				leading = append(leading, ms)
+			} else {
+				cur.samples = append(cur.samples, ms)
+			}
+			continue
+		}
+		if n, ok := parseASN(f); ok {
+			// traceroute -A prints it after the address, BSD traceroute -a before the host
+			if cur != nil && len(cur.samples) == 0 {
+				cur.ASN = n
+			} else {
+				asn = n
+			}
+			continue
+		}
+
+		host := ""
+		ip, err := netip.ParseAddr(f)
+		if err != nil {
+			if ip, err = parseEnclosed(next); err != nil {
+				continue
+			}
+			host = f
+			i++
+		} else if enclosed, err := parseEnclosed(next); err == nil && enclosed == ip {
+			i++
+		}
+		cur = h.reply(ip, host)
+		if asn != 0 {
+			cur.ASN, asn = asn, 0
+		}
+		if len(leading) > 0 {
+			p.Format = FormatTracert
+			cur.samples, leading = append(cur.samples, leading...), nil
+		}
+	}
+}
+
+// parseTracepath parses the fields after the ttl of tracepath, like
+//
+//	_gateway (192.168.1.1)   0.367ms asymm  2
+//	no reply
+func (p *Parser) parseTracepath(fields []string) {
+	h := p.cur
+	if len(fields) >= 2 && fields[0] == "no" && fields[1] == "reply" {
+		h.lost++
+		return
+	}
+	if len(fields) < 2 {
+		return
+	}
+
+	ip, err := netip.ParseAddr(fields[0])
+	host := ""
+	rest := fields[1:]
+	if err != nil {
+		host = fields[0]
+		if enclosed, err := parseEnclosed(fields[1]); err == nil {
+			ip, rest = enclosed, fields[2:]
+		}
+	}
+	r := h.reply(ip, host)
+	if len(rest) > 0 {
+		next := ""
+		if len(rest) > 1 {
+			next = rest[1]
+		}
+		if ms, n := parseRTT(rest[0], next); n > 0 {
+			r.samples = append(r.samples, ms)
+		}
+	}
+}
+
+// parseMTR parses the fields after the ttl of mtr --report, like
+//
+//	AS13335  one.one.one.one (1.1.1.1)   0.0%    10    8.1   8.3   8.0   9.1   0.3
+//	???                                 100.0    10    0.0   0.0   0.0   0.0   0.0
+func (p *Parser) parseMTR(fields []string) {
+	h := p.cur
+	columns := p.columns
+	if columns == nil {
+		columns = mtrColumns
+	}
+	if len(fields) < len(columns)+1 {
+		return
+	}
+	stats := fields[len(fields)-len(columns):]
+	fields = fields[:len(fields)-len(columns)]
+
+	var rtt RTT
+	hasRTT := false
+	h.stats = true
+	for i, name := range columns {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(stats[i], "%"), 64)
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "Loss%":
+			h.Loss = v
+		case "Snt":
+			h.Sent = int(v)
+		case "Avg":
+			rtt.Avg, hasRTT = v, true
+		case "Best":
+			rtt.Min = v
+		case "Wrst":
+			rtt.Max = v
+		}
+	}
+
+	var asn uint
+	if n, ok := parseASN(fields[0]); ok {
+		asn, fields = n, fields[1:]
+	} else if strings.HasPrefix(fields[0], "AS") {
+		// AS??? for addresses without an AS
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || fields[0] == "???" {
+		return
+	}
+
+	ip, err := netip.ParseAddr(fields[0])
+	host := ""
+	if err != nil {
+		host = fields[0]
+		if len(fields) > 1 {
+			ip, _ = parseEnclosed(fields[1])
+		}
+	}
+	r := h.reply(ip, host)
+	r.ASN = asn
+	if hasRTT && h.Loss < 100 {
+		r.RTT = &rtt
+	}
+}
+
+// reply returns the reply of ip in the hop, a new one is added if there is none
+func (h *Hop) reply(ip netip.Addr, host string) *Reply {
+	for _, r := range h.Replies {
+		if (ip.IsValid() && r.IP == ip) || (!ip.IsValid() && r.Host == host) {
+			return r
+		}
+	}
+	r := &Reply{IP: ip, Host: host}
+	if host == ip.String() {
+		r.Host = ""
+	}
+	h.Replies = append(h.Replies, r)
+	return r
+}
+
+// finish computes the round trip times and the loss of the probes
+func (h *Hop) finish() {
+	sent := h.lost
+	for _, r := range h.Replies {
+		if len(r.samples) == 0 {
+			continue
+		}
+		sent += len(r.samples)
+		rtt := RTT{Min: r.samples[0], Max: r.samples[0]}
+		sum := 0.0
+		for _, ms := range r.samples {
+			sum += ms
+			if ms < rtt.Min {
+				rtt.Min = ms
+			}
+			if ms > rtt.Max {
+				rtt.Max = ms
+			}
+		}
+		rtt.Avg = sum / float64(len(r.samples))
+		r.RTT = &rtt
+	}
+	if h.stats {
+		return
+	}
+	h.Sent = sent
+	if sent > 0 {
+		h.Loss = float64(h.lost) * 100 / float64(sent)
+	}
+}
+
+// parseRTT parses times like 0.3 ms, 0.3ms and <1 ms, which is taken as 1 ms,
+// and returns the number of fields used
+func parseRTT(f, next string) (float64, int) {
+	n := 1
+	if strings.HasSuffix(f, "ms") {
+		f = strings.TrimSuffix(f, "ms")
+	} else if next == "ms" {
+		n = 2
+	} else {
+		return 0, 0
+	}
+	f = strings.TrimPrefix(f, "<")
+	ms, err := strconv.ParseFloat(f, 64)
+	if err != nil || ms < 0 {
+		return 0, 0
+	}
+	return ms, n
+}
+
+// parseASN parses AS13335 and [AS13335], the first one of [AS13335/AS209242] is taken
+func parseASN(f string) (uint, bool) {
+	f = strings.TrimSuffix(strings.TrimPrefix(f, "["), "]")
+	f, _, _ = strings.Cut(f, "/")
+	if !strings.HasPrefix(f, "AS") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(f[2:], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(n), true
+}
+
+// parseEnclosed parses an address in parentheses or brackets
+func parseEnclosed(f string) (netip.Addr, error) {
+	if len(f) > 2 && (f[0] == '(' && f[len(f)-1] == ')' || f[0] == '[' && f[len(f)-1] == ']') {
+		return netip.ParseAddr(f[1 : len(f)-1])
+	}
+	return netip.Addr{}, errNotEnclosed
+}
+
+func indexOf(fields []string, suffix string) int {
+	for i, f := range fields {
+		if strings.HasSuffix(f, suffix) {
+			return i
+		}
+	}
+	return -1
+}
+
+func lastOf(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
@@ -0,0 +1,128 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseFile(t *testing.T, name string) *Parser {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	p := &Parser{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if h := p.Parse(scanner.Text()); h != nil {
+			p.Add(h)
+		}
+	}
+	if h := p.Flush(); h != nil {
+		p.Add(h)
+	}
+	return p
+}
+
+func formatHop(h *Hop) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%d %d/%.0f%%", h.TTL, h.Sent, h.Loss)
+	for _, r := range h.Replies {
+		fmt.Fprintf(&s, " %s", r.Name())
+		if r.IP.IsValid() && r.Host != "" {
+			fmt.Fprintf(&s, "(%s)", r.Host)
+		}
+		if r.RTT != nil {
+			fmt.Fprintf(&s, " %.1f/%.1f/%.1f", r.RTT.Min, r.RTT.Avg, r.RTT.Max)
+		}
+		if r.ASN != 0 {
+			fmt.Fprintf(&s, " AS%d", r.ASN)
+		}
+	}
+	return s.String()
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		file   string
+		format Format
+		target string
+		hops   []string
+	}{
+		{"traceroute.txt", FormatTraceroute, "one.one.one.one", []string{
+			"1 3/0% 192.168.1.1(_gateway) 0.3/0.3/0.3",
+			"2 3/100%",
+			"3 3/0% 10.0.0.1 5.1/5.1/5.1 100.64.0.1 5.9/6.1/6.2",
+			"4 3/33% 202.97.12.1 30.1/30.6/31.0 AS4134",
+			"5 3/0% 1.1.1.1(one.one.one.one) 40.0/42.0/44.0 AS13335",
+		}},
+		{"traceroute-bsd.txt", FormatTraceroute, "8.8.8.8", []string{
+			"1 3/0% 192.168.1.1 1.9/2.0/2.1",
+			"2 3/0% 61.152.1.1 8.0/8.0/8.0 AS4134 61.152.1.2 9.0/9.5/10.0 AS4134",
+			"3 3/100%",
+		}},
+		{"mtr.txt", FormatMTR, "", []string{
+			"1 10/0% 192.168.1.1 0.3/0.5/0.9",
+			"2 10/100%",
+			"3 10/20% 202.97.12.1 29.8/31.5/35.2 AS4134 202.97.12.2",
+			"4 10/0% 1.1.1.1(one.one.one.one) 39.9/40.3/41.0 AS13335",
+		}},
+		{"tracepath.txt", FormatTracepath, "", []string{
+			"1 2/0% 192.168.1.1(_gateway) 0.3/0.3/0.4",
+			"2 1/100%",
+			"3 1/0% 202.97.12.1 30.5/30.5/30.5",
+			"4 1/0% one.one.one.one 40.2/40.2/40.2",
+		}},
+		{"tracert.txt", FormatTracert, "one.one.one.one", []string{
+			"1 3/0% 192.168.1.1 1.0/1.0/1.0", // <1 ms is taken as 1 ms
+			"2 3/100%",
+			"3 3/0% 202.97.12.1(core.example.net) 30.0/31.0/32.0",
+			"4 3/0% 1.1.1.1(one.one.one.one) 40.0/40.3/41.0",
+		}},
+	} {
+		p := parseFile(t, tt.file)
+		if p.Format != tt.format || p.Target != tt.target {
+			t.Errorf("%s: format %s target %q, want %s %q", tt.file, p.Format, p.Target, tt.format, tt.target)
+		}
+		var got []string
+		for _, h := range p.Hops {
+			got = append(got, formatHop(h))
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.hops, "\n") {
+			t.Errorf("%s:\n%s\nwant\n%s", tt.file, strings.Join(got, "\n"), strings.Join(tt.hops, "\n"))
+		}
+	}
+}
+
+func TestCrossing(t *testing.T) {
+	p := parseFile(t, "traceroute.txt")
+	locations := map[string]string{"202.97.12.1": "CN", "1.1.1.1": "US"}
+	tr := &Trace{}
+	for _, h := range p.Hops {
+		for _, r := range h.Replies {
+			if r.IP.IsPrivate() || strings.HasPrefix(r.Name(), "100.64.") {
+				r.Class = "private"
+			}
+			r.CountryCode = locations[r.Name()]
+		}
+		h.Crossing = nil
+		tr.Add(h)
+	}
+
+	for i, h := range tr.Hops {
+		want := i == 4
+		if (h.Crossing != nil) != want {
+			t.Errorf("hop %d crossing = %+v", h.TTL, h.Crossing)
+		}
+	}
+	c := tr.Hops[4].Crossing
+	if fmt.Sprint(c.Country, c.ASN) != "[CN US] [4134 13335]" {
+		t.Errorf("crossing = %v %v", c.Country, c.ASN)
+	}
+}
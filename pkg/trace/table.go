@@ -0,0 +1,135 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/abc1763613206/nabili/pkg/common"
+)
+
+// tableColumns are the headers of Table, the last one is not padded
+var tableColumns = []string{"HOP", "IP", "LOSS", "RTT", "ASN", "LOCATION", "ISP", "HOST"}
+
+// Table writes hops as a table as they come, the columns grow to the widest value seen
+type Table struct {
+	w      io.Writer
+	widths []int
+}
+
+func NewTable(w io.Writer) *Table {
+	return &Table{w: w, widths: []int{3, 15, 6, 9, 8, 16, 12}}
+}
+
+// Write writes the rows of a hop, one per reply, after a line marking its Crossing
+func (t *Table) Write(h *Hop) error {
+	var s strings.Builder
+	if h.Crossing != nil {
+		var parts []string
+		if c := h.Crossing.Country; c != nil {
+			parts = append(parts, c[0]+" → "+c[1])
+		}
+		if c := h.Crossing.ASN; c != nil {
+			parts = append(parts, fmt.Sprintf("AS%d → AS%d", c[0], c[1]))
+		}
+		s.WriteString(color.YellowString("%*s  ── %s ──", t.widths[0], "", strings.Join(parts, ", ")) + "\n")
+	}
+
+	loss := strconv.FormatFloat(h.Loss, 'f', 1, 64) + "%"
+	if len(h.Replies) == 0 {
+		t.row(&s, []string{strconv.Itoa(h.TTL), "*", loss}, false)
+	}
+	for i, r := range h.Replies {
+		cells := make([]string, len(tableColumns))
+		if i == 0 {
+			cells[0], cells[2] = strconv.Itoa(h.TTL), loss
+		}
+		cells[1] = r.Name()
+		if r.RTT != nil {
+			cells[3] = strconv.FormatFloat(r.RTT.Avg, 'f', 1, 64) + " ms"
+		}
+		if r.ASN != 0 {
+			cells[4] = "AS" + strconv.FormatUint(uint64(r.ASN), 10)
+		}
+		cells[5] = strings.Join(nonEmpty(r.Country, r.Region, r.City), " ")
+		if cells[5] == "" {
+			// special-purpose addresses and databases without normalized fields
+			cells[5] = r.Info
+		}
+		cells[6] = r.ISP
+		if cells[6] == "" {
+			cells[6] = r.ASOrg
+		}
+		if r.IP.IsValid() {
+			cells[7] = r.Host
+		}
+		t.row(&s, cells, true)
+	}
+	_, err := io.WriteString(t.w, s.String())
+	return err
+}
+
+// WriteHeader writes the column headers
+func (t *Table) WriteHeader() error {
+	var s strings.Builder
+	t.row(&s, tableColumns, false)
+	_, err := io.WriteString(t.w, s.String())
+	return err
+}
+
+func (t *Table) row(s *strings.Builder, cells []string, colored bool) {
+	// drop the padding of trailing empty cells
+	for len(cells) > 0 && cells[len(cells)-1] == "" {
+		cells = cells[:len(cells)-1]
+	}
+	for i, cell := range cells {
+		if i > 0 {
+			s.WriteString("  ")
+		}
+		text := cell
+		switch {
+		case !colored:
+		case i == 1:
+			if strings.Contains(cell, ":") {
+				text = color.BlueString(cell)
+			} else {
+				text = color.GreenString(cell)
+			}
+		case i == 5:
+			text = color.RedString(cell)
+		}
+		// numbers are aligned to the right, the last text is not padded
+		right := i == 0 || i == 2 || i == 3
+		if i >= len(t.widths) || (i == len(cells)-1 && !right) {
+			s.WriteString(text)
+			continue
+		}
+
+		w := common.DisplayWidth(cell)
+		if w > t.widths[i] {
+			t.widths[i] = w
+		}
+		pad := strings.Repeat(" ", t.widths[i]-w)
+		if right {
+			s.WriteString(pad + text)
+		} else {
+			s.WriteString(text + pad)
+		}
+	}
+	s.WriteString("\n")
+}
+
+func nonEmpty(values ...string) []string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		// skip repeated names like 北京 北京
+		if v == "" || (len(parts) > 0 && parts[len(parts)-1] == v) {
+			continue
+		}
+		parts = append(parts, v)
+	}
+	return parts
+}
@@ -0,0 +1,122 @@
+// Package trace parses the output of traceroute, mtr --report, tracepath and Windows tracert into hops
+package trace
+
+import (
+	"net/netip"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// Format is the program a trace was printed by
+type Format string
+
+const (
+	FormatTraceroute Format = "traceroute"
+	FormatMTR        Format = "mtr"
+	FormatTracepath  Format = "tracepath"
+	FormatTracert    Format = "tracert"
+)
+
+// RTT are the round trip times of the probes in milliseconds
+type RTT struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+// Reply is an address that answered the probes of a hop
+type Reply struct {
+	IP   netip.Addr `json:"ip"`
+	Host string     `json:"host,omitempty"`
+	RTT  *RTT       `json:"rtt,omitempty"`
+
+	// ASN is taken from traceroute -A or mtr -z if present, ASOrg from the asn database
+	ASN   uint   `json:"asn,omitempty"`
+	ASOrg string `json:"as_org,omitempty"`
+
+	// Info is the text of the database result, Fields its normalized location
+	Info   string `json:"info,omitempty"`
+	Source string `json:"source,omitempty"`
+	Class  string `json:"class,omitempty"`
+	iprange.Fields
+
+	samples []float64
+}
+
+// Name is the address of the reply, or the host name if tracepath did not print the address
+func (r *Reply) Name() string {
+	if r.IP.IsValid() {
+		return r.IP.String()
+	}
+	return r.Host
+}
+
+// Hop is a TTL of the trace, it has no replies if every probe timed out
+type Hop struct {
+	TTL     int      `json:"ttl"`
+	Replies []*Reply `json:"replies"`
+	// Sent is the number of probes, Loss the percentage of them without reply
+	Sent int     `json:"sent"`
+	Loss float64 `json:"loss"`
+
+	// Crossing is set if the hop is in another country or autonomous system than the last hop before it
+	Crossing *Crossing `json:"crossing,omitempty"`
+
+	lost  int
+	stats bool // Sent and Loss are printed by mtr
+}
+
+// Crossing is a country or autonomous system boundary, the values are from and to
+type Crossing struct {
+	Country []string `json:"country,omitempty"`
+	ASN     []uint   `json:"asn,omitempty"`
+}
+
+// Trace is a parsed trace, see Parser
+type Trace struct {
+	Format Format `json:"format"`
+	Target string `json:"target,omitempty"`
+	Hops   []*Hop `json:"hops"`
+
+	last *Reply
+}
+
+// Add appends an annotated hop and sets its Crossing from the last located hop,
+// replies in private or other special-purpose ranges are not located
+func (t *Trace) Add(h *Hop) {
+	t.Hops = append(t.Hops, h)
+	for _, r := range h.Replies {
+		if r.Class != "" || (country(r) == "" && r.ASN == 0) {
+			continue
+		}
+		if t.last != nil {
+			var c Crossing
+			if from, to := country(t.last), country(r); from != "" && to != "" && from != to {
+				c.Country = []string{displayCountry(t.last), displayCountry(r)}
+			}
+			if t.last.ASN != 0 && r.ASN != 0 && t.last.ASN != r.ASN {
+				c.ASN = []uint{t.last.ASN, r.ASN}
+			}
+			if c.Country != nil || c.ASN != nil {
+				h.Crossing = &c
+			}
+		}
+		t.last = r
+		return
+	}
+}
+
+// country is the key compared for country crossings
+func country(r *Reply) string {
+	if r.CountryCode != "" {
+		return r.CountryCode
+	}
+	return r.Country
+}
+
+func displayCountry(r *Reply) string {
+	if r.Country != "" {
+		return r.Country
+	}
+	return r.CountryCode
+}
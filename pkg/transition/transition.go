@@ -0,0 +1,128 @@
+// Package transition decodes IPv4 addresses embedded in IPv6 transition addresses
+package transition
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+type Kind string
+
+const (
+	KindIPv4Mapped     Kind = "ipv4-mapped"
+	KindIPv4Compatible Kind = "ipv4-compatible"
+	KindNAT64          Kind = "nat64"
+	Kind6to4           Kind = "6to4"
+	KindTeredo         Kind = "teredo"
+	KindISATAP         Kind = "isatap"
+)
+
+// WellKnownNAT64 is the NAT64 prefix of RFC 6052, it is always decoded
+var WellKnownNAT64 = netip.MustParsePrefix("64:ff9b::/96")
+
+// Embedded is an IPv4 address embedded in an IPv6 address
+type Embedded struct {
+	Kind  Kind       `json:"kind"`
+	Outer netip.Addr `json:"outer"`
+	Inner netip.Addr `json:"inner"`
+	// Server and Port are the Teredo server and the mapped client port
+	Server *netip.Addr `json:"server,omitempty"`
+	Port   uint16      `json:"port,omitempty"`
+}
+
+func (e Embedded) String() string {
+	return fmt.Sprintf("%s %s", e.Kind, e.Inner)
+}
+
+// Decoder decodes transition addresses, NAT64 holds the local NAT64 prefixes
+type Decoder struct {
+	NAT64 []netip.Prefix
+}
+
+// NewDecoder returns a decoder with local NAT64 prefixes,
+// the prefix length must be one of the RFC 6052 layouts: 32, 40, 48, 56, 64 or 96
+func NewDecoder(nat64 ...netip.Prefix) (*Decoder, error) {
+	d := &Decoder{}
+	for _, p := range nat64 {
+		if !p.Addr().Is6() || p.Addr().Is4In6() {
+			return nil, fmt.Errorf("NAT64 前缀 %s 不是 IPv6 前缀", p)
+		}
+		switch p.Bits() {
+		case 32, 40, 48, 56, 64, 96:
+		default:
+			return nil, fmt.Errorf("NAT64 前缀 %s 的长度必须为 32、40、48、56、64 或 96", p)
+		}
+		d.NAT64 = append(d.NAT64, p.Masked())
+	}
+	return d, nil
+}
+
+// Decode returns the IPv4 address embedded in ip
+func (d *Decoder) Decode(ip netip.Addr) (Embedded, bool) {
+	if !ip.Is6() {
+		return Embedded{}, false
+	}
+	ip = ip.WithZone("")
+	b := ip.As16()
+	e := Embedded{Outer: ip}
+
+	switch {
+	case ip.Is4In6():
+		e.Kind, e.Inner = KindIPv4Mapped, ip.Unmap()
+	case isZero(b[:12]) && b[12] != 0:
+		// ::a.b.c.d, :: and ::1 are excluded by the first octet
+		e.Kind, e.Inner = KindIPv4Compatible, netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]})
+	case d.nat64(ip, &e):
+	case b[0] == 0x20 && b[1] == 0x02:
+		e.Kind, e.Inner = Kind6to4, netip.AddrFrom4([4]byte{b[2], b[3], b[4], b[5]})
+	case b[0] == 0x20 && b[1] == 0x01 && b[2] == 0 && b[3] == 0:
+		// the client port and address are obfuscated by inverting every bit
+		e.Kind = KindTeredo
+		server := netip.AddrFrom4([4]byte{b[4], b[5], b[6], b[7]})
+		e.Server = &server
+		e.Port = (uint16(b[10])<<8 | uint16(b[11])) ^ 0xffff
+		e.Inner = netip.AddrFrom4([4]byte{^b[12], ^b[13], ^b[14], ^b[15]})
+	case b[8]&^0x02 == 0 && b[9] == 0 && b[10] == 0x5e && b[11] == 0xfe:
+		// ISATAP interface identifier, the universal/local bit may be set
+		e.Kind, e.Inner = KindISATAP, netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]})
+	default:
+		return Embedded{}, false
+	}
+	return e, true
+}
+
+// nat64 extracts the IPv4 address with the RFC 6052 layout of the longest matching prefix,
+// bits 64 to 71 are reserved and skipped
+func (d *Decoder) nat64(ip netip.Addr, e *Embedded) bool {
+	var match netip.Prefix
+	for _, p := range append([]netip.Prefix{WellKnownNAT64}, d.NAT64...) {
+		if p.Contains(ip) && (!match.IsValid() || p.Bits() > match.Bits()) {
+			match = p
+		}
+	}
+	if !match.IsValid() {
+		return false
+	}
+
+	b := ip.As16()
+	var v4 [4]byte
+	pos := match.Bits() / 8
+	for i := range v4 {
+		if pos == 8 {
+			pos++
+		}
+		v4[i] = b[pos]
+		pos++
+	}
+	e.Kind, e.Inner = KindNAT64, netip.AddrFrom4(v4)
+	return true
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package transition
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	var prefixes []netip.Prefix
+	for _, p := range []string{
+		"2001:db8::/32",
+		"2001:db8:100::/40",
+		"2001:db8:122::/48",
+		"2001:db8:122:300::/56",
+		"2001:db8:122:344::/64",
+		"2001:db8:122:344::/96",
+	} {
+		prefixes = append(prefixes, netip.MustParsePrefix(p))
+	}
+	d, err := NewDecoder(prefixes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip    string
+		kind  Kind
+		inner string
+	}{
+		// RFC 6052 section 2.4 examples
+		{"2001:db8:c000:221::", KindNAT64, "192.0.2.33"},
+		{"2001:db8:1c0:2:21::", KindNAT64, "192.0.2.33"},
+		{"2001:db8:122:c000:2:2100::", KindNAT64, "192.0.2.33"},
+		{"2001:db8:122:3c0:0:221::", KindNAT64, "192.0.2.33"},
+		{"2001:db8:122:344:c0:2:2100:0", KindNAT64, "192.0.2.33"},
+		{"2001:db8:122:344::192.0.2.33", KindNAT64, "192.0.2.33"},
+		{"64:ff9b::8.8.8.8", KindNAT64, "8.8.8.8"},
+		{"::ffff:1.2.3.4", KindIPv4Mapped, "1.2.3.4"},
+		{"::1.2.3.4", KindIPv4Compatible, "1.2.3.4"},
+		{"2002:c000:22d::1", Kind6to4, "192.0.2.45"},
+		{"fe80::200:5efe:c000:22d", KindISATAP, "192.0.2.45"},
+		{"2400:da00::5efe:c000:22d", KindISATAP, "192.0.2.45"},
+		{"::", "", ""},
+		{"::1", "", ""},
+		{"2400:da00::1", "", ""},
+		{"1.2.3.4", "", ""},
+	}
+	for _, tt := range tests {
+		e, found := d.Decode(netip.MustParseAddr(tt.ip))
+		if found != (tt.kind != "") || e.Kind != tt.kind || (found && e.Inner.String() != tt.inner) {
+			t.Errorf("Decode(%s) = %s %s %v, want %s %s", tt.ip, e.Kind, e.Inner, found, tt.kind, tt.inner)
+		}
+	}
+}
+
+func TestDecodeTeredo(t *testing.T) {
+	d, _ := NewDecoder()
+	// RFC 4380 section 4 example
+	e, found := d.Decode(netip.MustParseAddr("2001:0000:4136:e378:8000:63bf:3fff:fdd2"))
+	if !found || e.Kind != KindTeredo {
+		t.Fatalf("Decode = %v %v", e, found)
+	}
+	if e.Server == nil || e.Server.String() != "65.54.227.120" || e.Inner.String() != "192.0.2.45" || e.Port != 40000 {
+		t.Errorf("got server %s client %s port %d", e.Server, e.Inner, e.Port)
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	for _, p := range []string{"2001:db8::/33", "64:ff9b::/128", "10.0.0.0/8"} {
+		if _, err := NewDecoder(netip.MustParsePrefix(p)); err == nil {
+			t.Errorf("NewDecoder(%s) succeeded", p)
+		}
+	}
+}
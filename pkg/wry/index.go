@@ -1,7 +1,10 @@
 package wry
 
 import (
+	"bytes"
 	"encoding/binary"
+	"net/netip"
+	"sort"
 )
 
 func (db *IPDB[uint32]) SearchIndexV4(ip uint32) uint32 {
@@ -17,6 +20,11 @@ func (db *IPDB[uint32]) SearchIndexV4(ip uint32) uint32 {
 		buf = db.Data[mid : mid+entryLen]
 		ipc = uint32(binary.LittleEndian.Uint32(buf[:ipLen]))
 
+		// a single entry index
+		if r == l {
+			return uint32(Bytes3ToUint32(buf[ipLen:entryLen]))
+		}
+
 		if r-l == entryLen {
 			if ip >= uint32(binary.LittleEndian.Uint32(db.Data[r:r+uint32(ipLen)])) {
 				buf = db.Data[r : r+entryLen]
@@ -34,31 +42,53 @@ func (db *IPDB[uint32]) SearchIndexV4(ip uint32) uint32 {
 	}
 }
 
-func (db *IPDB[uint64]) SearchIndexV6(ip uint64) uint32 {
-	ipLen := db.IPLen
-	entryLen := uint64(db.OffLen + db.IPLen)
-
-	buf := make([]byte, entryLen)
-	l, r, mid, ipc := db.IdxStart, db.IdxEnd, uint64(0), uint64(0)
+// SearchIndexV6 returns the record offset of the last entry starting at or before ip.
+// Index IPs hold the upper IPLen bytes of the start address, any IPLen up to 16 is compared in full.
+func (db *IPDB[T]) SearchIndexV6(ip netip.Addr) (uint32, error) {
+	key := ip.As16()
+	var err error
+	i := sort.Search(int(db.IPCnt), func(i int) bool {
+		e, eerr := db.entry(uint64(i))
+		if eerr != nil {
+			err = eerr
+			return true
+		}
+		start := entryStart(e, db.IPLen)
+		return bytes.Compare(start[:], key[:]) > 0
+	})
+	if err != nil {
+		return 0, err
+	}
+	if i > 0 {
+		i--
+	}
+	return db.entryOffset(uint64(i))
+}
 
-	for {
-		mid = (r-l)/entryLen/2*entryLen + l
-		buf = db.Data[mid : mid+entryLen]
-		ipc = uint64(binary.LittleEndian.Uint64(buf[:ipLen]))
+// entry returns the i-th index entry
+func (db *IPDB[T]) entry(i uint64) ([]byte, error) {
+	entryLen := uint64(db.IPLen) + uint64(db.OffLen)
+	pos := uint64(db.IdxStart) + i*entryLen
+	if pos+entryLen > uint64(len(db.Data)) {
+		return nil, ErrIndexOutOfRange
+	}
+	return db.Data[pos : pos+entryLen], nil
+}
 
-		if r-l == entryLen {
-			if ip >= uint64(binary.LittleEndian.Uint64(db.Data[r:r+uint64(ipLen)])) {
-				buf = db.Data[r : r+entryLen]
-			}
-			return Bytes3ToUint32(buf[ipLen:entryLen])
-		}
+func (db *IPDB[T]) entryOffset(i uint64) (uint32, error) {
+	e, err := db.entry(i)
+	if err != nil {
+		return 0, err
+	}
+	var b [4]byte
+	copy(b[:], e[db.IPLen:])
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
 
-		if ipc > ip {
-			r = mid
-		} else if ipc < ip {
-			l = mid
-		} else if ipc == ip {
-			return Bytes3ToUint32(buf[ipLen:entryLen])
-		}
+// entryStart reads the little endian IP of an IPv6 entry into the upper bytes of an address
+func entryStart(e []byte, ipLen uint8) (b [16]byte) {
+	for j := 0; j < int(ipLen) && j < 16; j++ {
+		b[j] = e[int(ipLen)-1-j]
 	}
+	return
 }
@@ -0,0 +1,171 @@
+package wry
+
+import (
+	"errors"
+	"net/netip"
+	"sort"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+var ErrIndexOutOfRange = errors.New("index entry out of range")
+
+// Iterator walks the index entries of an IPDB in ascending order,
+// use it like bufio.Scanner:
+//
+//	it := db.IterV4(lower, upper)
+//	for it.Next() {
+//		it.Start(), it.End(), it.Result()
+//	}
+//	err := it.Err()
+type Iterator[T ~uint32 | ~uint64] struct {
+	db *IPDB[T]
+	v4 bool
+
+	i     uint64
+	lower netip.Addr
+	upper netip.Addr
+
+	start  netip.Addr
+	end    netip.Addr
+	result Result
+	err    error
+}
+
+// IterV4 iterates a qqwry style index, where each record starts with its 4 bytes end IP.
+// Only records overlapping [lower, upper] are yielded, an invalid netip.Addr means no bound.
+func (db *IPDB[T]) IterV4(lower, upper netip.Addr) *Iterator[T] {
+	return db.iter(true, lower, upper)
+}
+
+// IterV6 iterates a ZX style index, where a record ends right before the next one starts
+// and the index IP holds the upper IPLen bytes of the address.
+// Only records overlapping [lower, upper] are yielded, an invalid netip.Addr means no bound.
+func (db *IPDB[T]) IterV6(lower, upper netip.Addr) *Iterator[T] {
+	return db.iter(false, lower, upper)
+}
+
+func (db *IPDB[T]) iter(v4 bool, lower, upper netip.Addr) *Iterator[T] {
+	it := &Iterator[T]{db: db, v4: v4}
+	if lower.IsValid() {
+		lower = it.addrOf(lower)
+		it.lower = lower
+		// the last entry starting at or before lower
+		i := sort.Search(int(db.IPCnt), func(i int) bool {
+			ip, err := it.entryIP(uint64(i))
+			return err != nil || ip.Compare(lower) > 0
+		})
+		if i > 0 {
+			it.i = uint64(i - 1)
+		}
+	}
+	if upper.IsValid() {
+		it.upper = it.addrOf(upper)
+	}
+	return it
+}
+
+// addrOf converts a bound to the family of the index
+func (it *Iterator[T]) addrOf(ip netip.Addr) netip.Addr {
+	if it.v4 {
+		return ip.Unmap()
+	}
+	return netip.AddrFrom16(ip.As16())
+}
+
+// Next advances to the next record, it returns false at the end or on error
+func (it *Iterator[T]) Next() bool {
+	for it.next() {
+		// a gap may precede lower in indexes with explicit end IPs
+		if !it.lower.IsValid() || it.end.Compare(it.lower) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *Iterator[T]) next() bool {
+	if it.err != nil || it.i >= uint64(it.db.IPCnt) {
+		return false
+	}
+
+	start, err := it.entryIP(it.i)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if it.upper.IsValid() && start.Compare(it.upper) > 0 {
+		return false
+	}
+	offset, err := it.entryOffset(it.i)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	var end netip.Addr
+	reader := NewReader(it.db.Data)
+	if it.v4 {
+		if uint64(offset)+4 > uint64(len(it.db.Data)) {
+			it.err = ErrIndexOutOfRange
+			return false
+		}
+		var b [4]byte
+		copy(b[:], it.db.Data[offset:offset+4])
+		b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+		end = netip.AddrFrom4(b)
+		err = reader.Parse(offset + 4)
+	} else {
+		end = iprange.LastAddr(start)
+		if it.i+1 < uint64(it.db.IPCnt) {
+			next, err := it.entryIP(it.i + 1)
+			if err != nil {
+				it.err = err
+				return false
+			}
+			end = next.Prev()
+		}
+		err = reader.Parse(offset)
+	}
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.start, it.end, it.result = start, end, reader.Result
+	it.i++
+	return true
+}
+
+func (it *Iterator[T]) Start() netip.Addr {
+	return it.start
+}
+
+func (it *Iterator[T]) End() netip.Addr {
+	return it.end
+}
+
+// Result returns the location of the current record, strings are not decoded from GBK
+func (it *Iterator[T]) Result() Result {
+	return it.result
+}
+
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// entryIP reads the little endian IP of an entry, IPv6 indexes hold the upper IPLen bytes
+func (it *Iterator[T]) entryIP(i uint64) (netip.Addr, error) {
+	e, err := it.db.entry(i)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if it.v4 {
+		return netip.AddrFrom4([4]byte{e[3], e[2], e[1], e[0]}), nil
+	}
+	return netip.AddrFrom16(entryStart(e, it.db.IPLen)), nil
+}
+
+func (it *Iterator[T]) entryOffset(i uint64) (uint32, error) {
+	return it.db.entryOffset(i)
+}
@@ -0,0 +1,179 @@
+package wry
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+type fixtureRecord struct {
+	start, end string
+	country    string
+	area       string
+}
+
+func putUint24(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16))
+}
+
+// qqwryFixture builds a qqwry file, repeated countries are written in redirect mode 2
+func qqwryFixture(records []fixtureRecord) IPDB[uint32] {
+	data := make([]byte, 8)
+	countries := map[string]uint32{}
+	offsets := make([]uint32, len(records))
+	for i, r := range records {
+		offsets[i] = uint32(len(data))
+		end := netip.MustParseAddr(r.end).As4()
+		data = binary.LittleEndian.AppendUint32(data, binary.BigEndian.Uint32(end[:]))
+		if off, found := countries[r.country]; found {
+			data = putUint24(append(data, RedirectMode2), off)
+		} else {
+			countries[r.country] = uint32(len(data))
+			data = append(append(data, r.country...), 0)
+		}
+		data = append(append(data, r.area...), 0)
+	}
+
+	idxStart := uint32(len(data))
+	for i, r := range records {
+		start := netip.MustParseAddr(r.start).As4()
+		data = binary.LittleEndian.AppendUint32(data, binary.BigEndian.Uint32(start[:]))
+		data = putUint24(data, offsets[i])
+	}
+	idxEnd := uint32(len(data)) - 7
+	binary.LittleEndian.PutUint32(data[0:], idxStart)
+	binary.LittleEndian.PutUint32(data[4:], idxEnd)
+
+	return IPDB[uint32]{
+		Data:     data,
+		OffLen:   3,
+		IPLen:    4,
+		IPCnt:    uint32(len(records)),
+		IdxStart: idxStart,
+		IdxEnd:   idxEnd,
+	}
+}
+
+// zxFixture builds a ZX file whose index holds the upper ipLen bytes of each start IP
+func zxFixture(records []fixtureRecord, ipLen uint8) IPDB[uint64] {
+	data := make([]byte, 24)
+	copy(data, "IPDB")
+	data[6], data[7] = 3, ipLen
+
+	offsets := make([]uint32, len(records))
+	for i, r := range records {
+		offsets[i] = uint32(len(data))
+		data = append(append(data, r.country...), 0)
+		data = append(append(data, r.area...), 0)
+	}
+
+	idxStart := uint64(len(data))
+	for i, r := range records {
+		start := netip.MustParseAddr(r.start).As16()
+		for j := int(ipLen) - 1; j >= 0; j-- {
+			data = append(data, start[j])
+		}
+		data = putUint24(data, offsets[i])
+	}
+	binary.LittleEndian.PutUint64(data[8:], uint64(len(records)))
+	binary.LittleEndian.PutUint64(data[16:], idxStart)
+
+	return IPDB[uint64]{
+		Data:     data,
+		OffLen:   3,
+		IPLen:    ipLen,
+		IPCnt:    uint64(len(records)),
+		IdxStart: idxStart,
+		IdxEnd:   idxStart + uint64(len(records))*uint64(3+ipLen),
+	}
+}
+
+func collect[T ~uint32 | ~uint64](t *testing.T, it *Iterator[T]) []fixtureRecord {
+	t.Helper()
+	var got []fixtureRecord
+	for it.Next() {
+		res := it.Result()
+		got = append(got, fixtureRecord{it.Start().String(), it.End().String(), res.Country, res.Area})
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func checkRecords(t *testing.T, got, want []fixtureRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records %v, want %d %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+var v4Records = []fixtureRecord{
+	{"0.0.0.0", "0.255.255.255", "IANA", "保留地址"},
+	{"1.0.0.0", "1.0.0.255", "Australia", "APNIC"},
+	{"1.0.1.0", "1.0.3.255", "China", "Telecom"},
+	{"1.0.4.0", "1.0.7.255", "Australia", "Victoria"},
+	{"1.0.8.0", "255.255.255.255", "China", ""},
+}
+
+func TestIterV4(t *testing.T) {
+	db := qqwryFixture(v4Records)
+	checkRecords(t, collect(t, db.IterV4(netip.Addr{}, netip.Addr{})), v4Records)
+
+	tests := []struct {
+		lower, upper string
+		want         []fixtureRecord
+	}{
+		{"1.0.0.0", "1.0.0.0", v4Records[1:2]},
+		{"1.0.2.0", "1.0.4.0", v4Records[2:4]},
+		{"::ffff:1.0.2.0", "", v4Records[2:]},
+		{"", "0.0.0.1", v4Records[:1]},
+		{"9.9.9.9", "", v4Records[4:]},
+	}
+	for _, tt := range tests {
+		var lower, upper netip.Addr
+		if tt.lower != "" {
+			lower = netip.MustParseAddr(tt.lower)
+		}
+		if tt.upper != "" {
+			upper = netip.MustParseAddr(tt.upper)
+		}
+		checkRecords(t, collect(t, db.IterV4(lower, upper)), tt.want)
+	}
+}
+
+func TestIterV4Truncated(t *testing.T) {
+	db := qqwryFixture(v4Records)
+	db.Data = db.Data[:len(db.Data)-3]
+	it := db.IterV4(netip.Addr{}, netip.Addr{})
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if it.Err() == nil || n != len(v4Records)-1 {
+		t.Errorf("got %d records and error %v on a truncated index", n, it.Err())
+	}
+}
+
+var v6Records = []fixtureRecord{
+	{"::", "2000:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "IANA", ""},
+	{"2001::", "2001:db7:ffff:ffff:ffff:ffff:ffff:ffff", "Teredo", ""},
+	{"2001:db8::", "2001:db8:0:ffff:ffff:ffff:ffff:ffff", "Documentation", "RFC3849"},
+	{"2001:db8:1::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "Unknown", ""},
+}
+
+func TestIterV6(t *testing.T) {
+	db := zxFixture(v6Records, 8)
+	checkRecords(t, collect(t, db.IterV6(netip.Addr{}, netip.Addr{})), v6Records)
+
+	got := collect(t, db.IterV6(netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8:1::")))
+	checkRecords(t, got, v6Records[2:])
+
+	got = collect(t, db.IterV6(netip.Addr{}, netip.MustParseAddr("2001::")))
+	checkRecords(t, got, v6Records[:2])
+}
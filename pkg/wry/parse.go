@@ -7,22 +7,33 @@ const (
 	RedirectMode2 = 0x02
 )
 
-func (r *Reader) Parse(offset uint32) {
+// maxRedirects caps the chained mode 1 redirects, real databases use at most one
+const maxRedirects = 8
+
+// Parse reads the location at offset into Result,
+// it fails on offsets out of the data, strings without terminator and redirect loops
+func (r *Reader) Parse(offset uint32) error {
 	if offset != 0 {
 		r.seekAbs(offset)
 	}
 
-	switch r.readMode() {
-	case RedirectMode1:
-		r.readOffset(true)
-		r.Parse(0)
-	case RedirectMode2:
-		r.Result.Country = r.parseRedMode2()
-		r.Result.Area = r.readArea()
-	default:
-		r.seekBack()
-		r.Result.Country = r.readString(true)
-		r.Result.Area = r.readArea()
+	for depth := 0; ; depth++ {
+		if depth > maxRedirects {
+			return ErrRedirectDepth
+		}
+		switch r.readMode() {
+		case RedirectMode1:
+			r.readOffset(true)
+			continue
+		case RedirectMode2:
+			r.Result.Country = r.parseRedMode2()
+			r.Result.Area = r.readArea()
+		default:
+			r.seekBack()
+			r.Result.Country = r.readString(true)
+			r.Result.Area = r.readArea()
+		}
+		return r.err
 	}
 }
 
@@ -0,0 +1,63 @@
+package wry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	pad := []byte{0, 0, 0, 0}
+	tests := []struct {
+		name   string
+		data   []byte
+		offset uint32
+		want   error
+	}{
+		{"redirect loop", append(pad, RedirectMode1, 4, 0, 0), 4, ErrRedirectDepth},
+		{"unterminated", append(pad, 'a', 'b', 'c'), 4, ErrUnterminated},
+		{"unterminated area", append(pad, 'a', 0, 'b'), 4, ErrUnterminated},
+		{"offset", append(pad, 'a', 0), 100, ErrOffsetOutOfRange},
+		{"redirect offset", append(pad, RedirectMode2, 0xff, 0xff, 0xff, 0), 4, ErrOffsetOutOfRange},
+		{"truncated redirect", append(pad, RedirectMode1, 4), 4, ErrOffsetOutOfRange},
+		{"area offset", append(pad, 'a', 0, RedirectMode2, 0xff, 0, 0), 4, ErrOffsetOutOfRange},
+	}
+	for _, tt := range tests {
+		r := NewReader(tt.data)
+		if err := r.Parse(tt.offset); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Parse = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseRedirects(t *testing.T) {
+	data := []byte{
+		0, 0, 0, 0,
+		'a', 0, 'b', 0, // 4: country and area
+		RedirectMode1, 4, 0, 0, // 8: whole location at 4
+		RedirectMode2, 4, 0, 0, RedirectMode2, 6, 0, 0, // 12: both strings redirected
+		RedirectMode1, 8, 0, 0, // 20: chained mode 1
+		'c', 0, RedirectMode1, 0, 0, 0, // 24: area redirected to offset 0
+	}
+	for offset, want := range map[uint32]Result{
+		4:  {"a", "b"},
+		8:  {"a", "b"},
+		12: {"a", "b"},
+		20: {"a", "b"},
+		24: {"c", ""},
+	} {
+		r := NewReader(data)
+		if err := r.Parse(offset); err != nil || r.Result != want {
+			t.Errorf("Parse(%d) = %v %v, want %v", offset, r.Result, err, want)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 0, 'a', 0, 'b', 0}, uint32(4))
+	f.Add([]byte{0, 0, 0, 0, RedirectMode1, 4, 0, 0}, uint32(4))
+	f.Add([]byte{0, 0, 0, 0, RedirectMode2, 8, 0, 0, 'a', 0}, uint32(4))
+	f.Fuzz(func(t *testing.T, data []byte, offset uint32) {
+		r := NewReader(data)
+		_ = r.Parse(offset)
+	})
+}
@@ -0,0 +1,216 @@
+package wry
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+)
+
+// Record is a range and its location to write
+type Record struct {
+	Start   netip.Addr
+	End     netip.Addr
+	Country string
+	Area    string
+}
+
+const maxOffset = 1<<24 - 1
+
+// locationWriter writes the country and area of records,
+// repeated strings are written as redirects to their first copy
+type locationWriter struct {
+	data      []byte
+	strings   map[string]uint32
+	locations map[[2]string]uint32
+}
+
+func newLocationWriter(data []byte) *locationWriter {
+	return &locationWriter{
+		data:      data,
+		strings:   make(map[string]uint32),
+		locations: make(map[[2]string]uint32),
+	}
+}
+
+// write writes a location, reusing a whole location in redirect mode 1
+// or a single string in redirect mode 2
+func (lw *locationWriter) write(country, area string) error {
+	if off, found := lw.locations[[2]string{country, area}]; found {
+		lw.data = putOffset(append(lw.data, RedirectMode1), off)
+		return nil
+	}
+	lw.locations[[2]string{country, area}] = uint32(len(lw.data))
+
+	if off, found := lw.strings[country]; found && country != "" {
+		lw.data = putOffset(append(lw.data, RedirectMode2), off)
+	} else {
+		lw.writeString(country)
+	}
+	if off, found := lw.strings[area]; found && area != "" {
+		lw.data = putOffset(append(lw.data, RedirectMode2), off)
+	} else {
+		lw.writeString(area)
+	}
+
+	if len(lw.data) > maxOffset {
+		return errors.New("数据超过 16MiB，无法使用 3 字节偏移")
+	}
+	return nil
+}
+
+func (lw *locationWriter) writeString(s string) {
+	if _, found := lw.strings[s]; !found && s != "" {
+		lw.strings[s] = uint32(len(lw.data))
+	}
+	lw.data = append(append(lw.data, s...), 0)
+}
+
+func putOffset(b []byte, off uint32) []byte {
+	return append(b, byte(off), byte(off>>8), byte(off>>16))
+}
+
+// WriteQQwry writes sorted IPv4 records as a qqwry file with GBK strings
+func WriteQQwry(w io.Writer, records []Record) error {
+	if len(records) == 0 {
+		return errors.New("没有可写入的记录")
+	}
+	enc := simplifiedchinese.GBK.NewEncoder()
+
+	for i, r := range records {
+		if !r.Start.Is4() || !r.End.Is4() || r.End.Less(r.Start) || (i > 0 && !records[i-1].End.Less(r.Start)) {
+			return fmt.Errorf("记录 %s - %s 不是有序的 IPv4 地址段", r.Start, r.End)
+		}
+	}
+	records = fillGaps(records, netip.IPv4Unspecified())
+
+	lw := newLocationWriter(make([]byte, 8))
+	offsets := make([]uint32, len(records))
+	for i, r := range records {
+		country, err := enc.String(r.Country)
+		if err != nil {
+			return fmt.Errorf("%s 无法编码为 GBK: %w", r.Country, err)
+		}
+		area, err := enc.String(r.Area)
+		if err != nil {
+			return fmt.Errorf("%s 无法编码为 GBK: %w", r.Area, err)
+		}
+
+		offsets[i] = uint32(len(lw.data))
+		end := r.End.As4()
+		lw.data = binary.LittleEndian.AppendUint32(lw.data, binary.BigEndian.Uint32(end[:]))
+		if err := lw.write(country, area); err != nil {
+			return err
+		}
+	}
+
+	data := lw.data
+	idxStart := uint32(len(data))
+	for i, r := range records {
+		start := r.Start.As4()
+		data = binary.LittleEndian.AppendUint32(data, binary.BigEndian.Uint32(start[:]))
+		data = putOffset(data, offsets[i])
+	}
+	binary.LittleEndian.PutUint32(data[0:], idxStart)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data))-7)
+
+	_, err := w.Write(data)
+	return err
+}
+
+// WriteZX writes sorted IPv6 records as a ZX file with UTF-8 strings,
+// the index holds the upper ipLen bytes of each start IP so ranges must be aligned to them.
+func WriteZX(w io.Writer, records []Record, ipLen uint8) error {
+	if len(records) == 0 {
+		return errors.New("没有可写入的记录")
+	}
+	if ipLen == 0 || ipLen > 16 {
+		return fmt.Errorf("不支持的 IP 长度 %d", ipLen)
+	}
+
+	for i, r := range records {
+		if r.Start.Is4() || r.End.Is4() || r.End.Less(r.Start) || (i > 0 && !records[i-1].End.Less(r.Start)) {
+			return fmt.Errorf("记录 %s - %s 不是有序的 IPv6 地址段", r.Start, r.End)
+		}
+		if !alignedStart(r.Start, ipLen) || !alignedEnd(r.End, ipLen) {
+			return fmt.Errorf("记录 %s - %s 未按 %d 字节对齐", r.Start, r.End, ipLen)
+		}
+	}
+	filled := fillGaps(records, netip.IPv6Unspecified())
+
+	header := make([]byte, 24)
+	copy(header, "IPDB")
+	binary.LittleEndian.PutUint16(header[4:], 1)
+	header[6], header[7] = 3, ipLen
+
+	lw := newLocationWriter(header)
+	offsets := make([]uint32, len(filled))
+	for i, r := range filled {
+		offsets[i] = uint32(len(lw.data))
+		if err := lw.write(r.Country, r.Area); err != nil {
+			return err
+		}
+	}
+
+	data := lw.data
+	idxStart := uint64(len(data))
+	for i, r := range filled {
+		start := r.Start.As16()
+		for j := int(ipLen) - 1; j >= 0; j-- {
+			data = append(data, start[j])
+		}
+		data = putOffset(data, offsets[i])
+	}
+	binary.LittleEndian.PutUint64(data[8:], uint64(len(filled)))
+	binary.LittleEndian.PutUint64(data[16:], idxStart)
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(data); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// fillGaps covers the address space from first to the end with records,
+// gaps are filled with empty locations since lookups take the last record starting before an IP
+func fillGaps(records []Record, first netip.Addr) []Record {
+	filled := make([]Record, 0, len(records))
+	next := first
+	for _, r := range records {
+		if next.Less(r.Start) {
+			filled = append(filled, Record{Start: next, End: r.Start.Prev()})
+		}
+		filled = append(filled, r)
+		next = r.End.Next()
+	}
+	if next.IsValid() {
+		filled = append(filled, Record{Start: next, End: iprange.LastAddr(next)})
+	}
+	return filled
+}
+
+func alignedStart(ip netip.Addr, ipLen uint8) bool {
+	b := ip.As16()
+	for _, c := range b[ipLen:] {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func alignedEnd(ip netip.Addr, ipLen uint8) bool {
+	b := ip.As16()
+	for _, c := range b[ipLen:] {
+		if c != 0xff {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package wry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+func toRecords(records []fixtureRecord) []Record {
+	out := make([]Record, len(records))
+	for i, r := range records {
+		out[i] = Record{
+			Start:   netip.MustParseAddr(r.start),
+			End:     netip.MustParseAddr(r.end),
+			Country: r.country,
+			Area:    r.area,
+		}
+	}
+	return out
+}
+
+// writeQQwryDB opens the qqwry database written by WriteQQwry
+func writeQQwryDB(t *testing.T, records []fixtureRecord) IPDB[uint32] {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteQQwry(&buf, toRecords(records)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	start := binary.LittleEndian.Uint32(data[:4])
+	end := binary.LittleEndian.Uint32(data[4:8])
+	return IPDB[uint32]{
+		Data:     data,
+		OffLen:   3,
+		IPLen:    4,
+		IPCnt:    (end-start)/7 + 1,
+		IdxStart: start,
+		IdxEnd:   end,
+	}
+}
+
+// writeZXDB opens the ZX database written by WriteZX
+func writeZXDB(t *testing.T, records []fixtureRecord, ipLen uint8) IPDB[uint64] {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteZX(&buf, toRecords(records), ipLen); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	count := binary.LittleEndian.Uint64(data[8:16])
+	start := binary.LittleEndian.Uint64(data[16:24])
+	return IPDB[uint64]{
+		Data:     data,
+		OffLen:   data[6],
+		IPLen:    data[7],
+		IPCnt:    count,
+		IdxStart: start,
+		IdxEnd:   start + count*uint64(data[6]+data[7]),
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	records := []fixtureRecord{
+		{"0.0.0.0", "0.255.255.255", "IANA", "保留地址"},
+		{"1.0.0.0", "1.0.0.255", "澳大利亚", "APNIC"},
+		{"1.0.1.0", "1.0.3.255", "中国–福建–福州", "电信"},
+		{"1.0.4.0", "1.0.7.255", "澳大利亚", "维多利亚"},
+		{"1.0.8.0", "255.255.255.255", "中国", ""},
+	}
+	db := writeQQwryDB(t, records)
+	it := db.IterV4(netip.Addr{}, netip.Addr{})
+	var got []fixtureRecord
+	for it.Next() {
+		res := it.Result()
+		res.DecodeGBK()
+		got = append(got, fixtureRecord{it.Start().String(), it.End().String(), res.Country, res.Area})
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, got, records)
+
+	for _, ipLen := range []uint8{8, 16} {
+		db := writeZXDB(t, v6Records, ipLen)
+		checkRecords(t, collect(t, db.IterV6(netip.Addr{}, netip.Addr{})), v6Records)
+	}
+}
+
+func TestWriteQQwryRedirects(t *testing.T) {
+	records := []fixtureRecord{
+		{"0.0.0.0", "0.255.255.255", "中国–北京–北京", "联通"},
+		{"1.0.0.0", "1.0.0.255", "中国–北京–北京", "电信"},
+		{"1.0.1.0", "1.0.1.255", "美国", "联通"},
+		{"1.0.2.0", "255.255.255.255", "中国–北京–北京", "联通"},
+	}
+	db := writeQQwryDB(t, records)
+
+	modes := []byte{0, RedirectMode2, 0, RedirectMode1}
+	it := db.IterV4(netip.Addr{}, netip.Addr{})
+	for i := 0; it.Next(); i++ {
+		offset, _ := it.entryOffset(uint64(i))
+		if mode := db.Data[offset+4]; (modes[i] == 0 && mode <= RedirectMode2) || (modes[i] != 0 && mode != modes[i]) {
+			t.Errorf("record %d starts with mode %d, want %d", i, mode, modes[i])
+		}
+		res := it.Result()
+		res.DecodeGBK()
+		if res.Country != records[i].country || res.Area != records[i].area {
+			t.Errorf("record %d = %s %s, want %s %s", i, res.Country, res.Area, records[i].country, records[i].area)
+		}
+	}
+
+	// the third record redirects its area to the first one, 美国 is 4 bytes in GBK
+	offset, _ := it.entryOffset(2)
+	if area := db.Data[offset+4+4+1]; area != RedirectMode2 {
+		t.Errorf("area of record 2 starts with %d, want redirect mode 2", area)
+	}
+}
+
+func TestWriteQQwryInvalid(t *testing.T) {
+	tests := [][]fixtureRecord{
+		nil,
+		{{"::1", "::2", "a", ""}},
+		{{"1.0.0.0", "1.0.0.255", "a", ""}, {"1.0.0.255", "1.0.1.0", "b", ""}},
+		{{"1.0.0.9", "1.0.0.1", "a", ""}},
+		{{"1.0.0.0", "1.0.0.1", "\U0001F600", ""}},
+	}
+	for _, records := range tests {
+		if err := WriteQQwry(&bytes.Buffer{}, toRecords(records)); err == nil {
+			t.Errorf("WriteQQwry(%v) succeeded", records)
+		}
+	}
+}
+
+func TestWriteZXGaps(t *testing.T) {
+	db := writeZXDB(t, []fixtureRecord{
+		{"2001::", "2001::1:ffff:ffff:ffff:ffff", "a", ""},
+		{"2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "b", ""},
+	}, 8)
+	want := []fixtureRecord{
+		{"::", "2000:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "", ""},
+		{"2001::", "2001::1:ffff:ffff:ffff:ffff", "a", ""},
+		{"2001:0:0:2::", "2001:db7:ffff:ffff:ffff:ffff:ffff:ffff", "", ""},
+		{"2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "b", ""},
+		{"2001:db8:0:1::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "", ""},
+	}
+	checkRecords(t, collect(t, db.IterV6(netip.Addr{}, netip.Addr{})), want)
+
+	unaligned := toRecords([]fixtureRecord{{"2001::1", "2001::ffff", "a", ""}})
+	if err := WriteZX(&bytes.Buffer{}, unaligned, 8); err == nil {
+		t.Error("WriteZX accepted a range not aligned to 8 bytes")
+	}
+	if err := WriteZX(&bytes.Buffer{}, unaligned, 16); err != nil {
+		t.Errorf("WriteZX with full IPs: %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package wry
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -19,11 +20,20 @@ type IPDB[T ~uint32 | ~uint64] struct {
 	IdxEnd   T
 }
 
+var (
+	ErrOffsetOutOfRange = errors.New("offset out of range")
+	ErrUnterminated     = errors.New("string not terminated")
+	ErrRedirectDepth    = errors.New("too many redirects")
+)
+
 type Reader struct {
 	s []byte
 	i uint32 // current reading index
 	l uint32 // last reading index
 
+	// err is the first error while reading, later reads return zero values
+	err error
+
 	Result Result
 }
 
@@ -51,6 +61,13 @@ func (r *Reader) seekBack() {
 
 func (r *Reader) read(length uint32) []byte {
 	rs := make([]byte, length)
+	if r.err != nil {
+		return rs
+	}
+	if uint64(r.i)+uint64(length) > uint64(len(r.s)) {
+		r.err = fmt.Errorf("%w: %d", ErrOffsetOutOfRange, r.i)
+		return rs
+	}
 	copy(rs, r.s[r.i:])
 	r.l = r.i
 	r.i += length
@@ -58,6 +75,13 @@ func (r *Reader) read(length uint32) []byte {
 }
 
 func (r *Reader) readMode() (mode byte) {
+	if r.err != nil {
+		return 0
+	}
+	if uint64(r.i) >= uint64(len(r.s)) {
+		r.err = fmt.Errorf("%w: %d", ErrOffsetOutOfRange, r.i)
+		return 0
+	}
 	mode = r.s[r.i]
 	r.l = r.i
 	r.i += 1
@@ -76,7 +100,18 @@ func (r *Reader) readOffset(follow bool) uint32 {
 }
 
 func (r *Reader) readString(seek bool) string {
+	if r.err != nil {
+		return ""
+	}
+	if uint64(r.i) >= uint64(len(r.s)) {
+		r.err = fmt.Errorf("%w: %d", ErrOffsetOutOfRange, r.i)
+		return ""
+	}
 	length := bytes.IndexByte(r.s[r.i:], 0)
+	if length < 0 {
+		r.err = fmt.Errorf("%w: %d", ErrUnterminated, r.i)
+		return ""
+	}
 	str := string(r.s[r.i : r.i+uint32(length)])
 	if seek {
 		r.l = r.i
@@ -7,8 +7,10 @@ import (
 	"io"
 	"log"
 	"net"
+	"net/netip"
 	"os"
 
+	"github.com/abc1763613206/nabili/pkg/iprange"
 	"github.com/abc1763613206/nabili/pkg/wry"
 )
 
@@ -40,7 +42,15 @@ func NewZXwry(filePath string) (*ZXwry, error) {
 	}
 
 	if !CheckFile(fileData) {
-		log.Fatalln("ZX IPv6数据库存在错误，请重新下载")
+		return nil, errors.New("ZX IPv6数据库存在错误，请重新下载")
+	}
+	return FromBytes(fileData)
+}
+
+// FromBytes opens a ZX database from its content
+func FromBytes(fileData []byte) (*ZXwry, error) {
+	if !CheckFile(fileData) {
+		return nil, errors.New("ZX IPv6数据库存在错误")
 	}
 
 	header := fileData[:24]
@@ -49,7 +59,7 @@ func NewZXwry(filePath string) (*ZXwry, error) {
 
 	start := binary.LittleEndian.Uint64(header[16:24])
 	counts := binary.LittleEndian.Uint64(header[8:16])
-	end := start + counts*11
+	end := start + counts*uint64(offLen+ipLen)
 
 	return &ZXwry{
 		IPDB: wry.IPDB[uint64]{
@@ -69,16 +79,19 @@ func (db *ZXwry) Find(query string, _ ...string) (result fmt.Stringer, err error
 	if ip == nil {
 		return nil, errors.New("query should be IPv6")
 	}
-	ip6 := ip.To16()
-	if ip6 == nil {
+	ip6, ok := netip.AddrFromSlice(ip.To16())
+	if !ok {
 		return nil, errors.New("query should be IPv6")
 	}
-	ip6 = ip6[:8]
-	ipu64 := binary.BigEndian.Uint64(ip6)
 
-	offset := db.SearchIndexV6(ipu64)
+	offset, err := db.SearchIndexV6(ip6)
+	if err != nil {
+		return nil, err
+	}
 	reader := wry.NewReader(db.Data)
-	reader.Parse(offset)
+	if err := reader.Parse(offset); err != nil {
+		return nil, err
+	}
 	return reader.Result, nil
 }
 
@@ -98,12 +111,41 @@ func CheckFile(data []byte) bool {
 		return false
 	}
 	header := data[:24]
+	offLen, ipLen := header[6], header[7]
+	// IP fields wider than 8 bytes hold more of the address, up to the full 16 bytes
+	if offLen == 0 || offLen > 4 || ipLen == 0 || ipLen > 16 {
+		return false
+	}
 	start := binary.LittleEndian.Uint64(header[16:24])
 	counts := binary.LittleEndian.Uint64(header[8:16])
-	end := start + counts*11
-	if start >= end || uint64(len(data)) < end {
+	if counts > uint64(len(data)) {
+		return false
+	}
+	end := start + counts*uint64(offLen+ipLen)
+	if start < 24 || start >= end || uint64(len(data)) < end {
 		return false
 	}
 
 	return true
 }
+
+// Ranges walks every record of the index
+func (db *ZXwry) Ranges(fn func(iprange.Range) error) error {
+	it := db.IterV6(netip.Addr{}, netip.Addr{})
+	for it.Next() {
+		res := it.Result()
+		res.Trim()
+		err := fn(iprange.Range{
+			Start:  it.Start(),
+			End:    it.End(),
+			Fields: iprange.ParseLocation(res.Country, res.Area),
+		})
+		if err == iprange.ErrStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,142 @@
+package zxipv6wry
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abc1763613206/nabili/pkg/iprange"
+	"github.com/abc1763613206/nabili/pkg/wry"
+)
+
+func newFixture(t *testing.T, records []wry.Record, ipLen uint8) *ZXwry {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := wry.WriteZX(&buf, records, ipLen); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckFile(buf.Bytes()) {
+		t.Fatalf("CheckFile rejected a database with IPLen %d", ipLen)
+	}
+	path := filepath.Join(t.TempDir(), "zxipv6wry.db")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewZXwry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func record(start, end, country string) wry.Record {
+	return wry.Record{Start: netip.MustParseAddr(start), End: netip.MustParseAddr(end), Country: country}
+}
+
+func checkFind(t *testing.T, db *ZXwry, tests map[string]string) {
+	t.Helper()
+	for ip, want := range tests {
+		res, err := db.Find(ip)
+		if err != nil {
+			t.Errorf("Find(%s): %v", ip, err)
+			continue
+		}
+		if res.String() != want {
+			t.Errorf("IPLen %d: Find(%s) = %q, want %q", db.IPLen, ip, res, want)
+		}
+	}
+}
+
+func TestFindIPLen8(t *testing.T) {
+	db := newFixture(t, []wry.Record{
+		record("2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "文档"),
+		record("2400:3200::", "2400:3200:ffff:ffff:ffff:ffff:ffff:ffff", "中国 阿里云"),
+	}, 8)
+	checkFind(t, db, map[string]string{
+		"2001:db8::1":      "文档",
+		"2001:db8:0:1::1":  "",
+		"2400:3200::1":     "中国 阿里云",
+		"2400:3200:1:2::3": "中国 阿里云",
+		"::1":              "",
+		"ffff::1":          "",
+	})
+}
+
+// records inside the same /64 are only told apart with wider IP fields
+func TestFindFullPrecision(t *testing.T) {
+	records := []wry.Record{
+		record("2001:db8::", "2001:db8::ffff", "A"),
+		record("2001:db8::1:0", "2001:db8::1:ffff", "B"),
+		record("2001:db8::8000:0:0:0", "2001:db8::ffff:ffff:ffff:ffff", "C"),
+		record("2001:db8:0:1::", "2001:db8:0:1:ffff:ffff:ffff:ffff", "D"),
+	}
+	want := map[string]string{
+		"2001:db8::1":                "A",
+		"2001:db8::ffff":             "A",
+		"2001:db8::1:0":              "B",
+		"2001:db8::1:1234":           "B",
+		"2001:db8::2:0":              "",
+		"2001:db8::8000:0:0:1":       "C",
+		"2001:db8:0:0:ffff::":        "C",
+		"2001:db8:0:1::42":           "D",
+		"2001:db8:0:2::":             "",
+		"ffff:ffff:ffff:ffff:ffff::": "",
+	}
+	checkFind(t, newFixture(t, records, 16), want)
+
+	// 12 byte IP fields need ranges aligned to /96
+	if err := wry.WriteZX(&bytes.Buffer{}, records, 12); err == nil {
+		t.Error("WriteZX accepted ranges not aligned to 12 bytes")
+	}
+	records[0].End = netip.MustParseAddr("2001:db8::ffff:ffff")
+	records = append(records[:1], records[2:]...)
+	delete(want, "2001:db8::1:0")
+	delete(want, "2001:db8::1:1234")
+	want["2001:db8::2:0"] = "A"
+	checkFind(t, newFixture(t, records, 12), want)
+}
+
+func TestCheckFile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := wry.WriteZX(&buf, []wry.Record{record("2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "A")}, 8); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if !CheckFile(data) {
+		t.Fatal("CheckFile rejected a valid database")
+	}
+	for name, mutate := range map[string]func([]byte){
+		"magic":     func(b []byte) { b[0] = 'X' },
+		"ip length": func(b []byte) { b[7] = 17 },
+		"truncated": func(b []byte) { b[8] = 0xff },
+	} {
+		b := append([]byte(nil), data...)
+		mutate(b)
+		if CheckFile(b) {
+			t.Errorf("CheckFile accepted a database with a bad %s", name)
+		}
+	}
+}
+
+func FuzzCheckFile(f *testing.F) {
+	for _, ipLen := range []uint8{8, 16} {
+		var buf bytes.Buffer
+		_ = wry.WriteZX(&buf, []wry.Record{record("2001:db8::", "2001:db8::ffff:ffff:ffff:ffff", "A")}, ipLen)
+		f.Add(buf.Bytes())
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if !CheckFile(data) {
+			return
+		}
+		db, err := FromBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ip := range []string{"::", "2001:db8::1", "ffff::1"} {
+			_, _ = db.Find(ip)
+		}
+		_ = db.Ranges(func(iprange.Range) error { return nil })
+	})
+}